@@ -0,0 +1,423 @@
+package btcmarkets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Base58 alphabets used by Bitcoin-derived currencies and by XRP.
+const (
+	base58BitcoinAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+	base58RippleAlphabet  = "rpshnaf39wBUDNEGHJKLM4PQRST7VWXYZ2bcdeCg65jkm8oFqi1tuvAxyz"
+)
+
+// bech32Charset is the data character set shared by Bech32 and CashAddr.
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Bech32 checksum constants, for the original encoding (segwit version 0) and
+// Bech32m (segwit version 1 onwards).
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// cashAddrPrefix is the network prefix of Bitcoin Cash CashAddr addresses.
+const cashAddrPrefix = "bitcoincash"
+
+// addressFormat describes the valid address formats of a currency.
+type addressFormat struct {
+	alphabet string
+	versions []byte
+	bech32   string
+	cashAddr bool
+	ethereum bool
+}
+
+// addressFormats are the address formats accepted for each currency.
+var addressFormats = map[Currency]addressFormat{
+	CurrencyBitcoin: {
+		alphabet: base58BitcoinAlphabet,
+		versions: []byte{0x00, 0x05},
+		bech32:   "bc",
+	},
+	CurrencyLitecoin: {
+		alphabet: base58BitcoinAlphabet,
+		versions: []byte{0x30, 0x32, 0x05},
+		bech32:   "ltc",
+	},
+	CurrencyBcash: {
+		alphabet: base58BitcoinAlphabet,
+		versions: []byte{0x00, 0x05},
+		cashAddr: true,
+	},
+	CurrencyEthereum:   {ethereum: true},
+	CurrencyEthClassic: {ethereum: true},
+	CurrencyRipple: {
+		alphabet: base58RippleAlphabet,
+		versions: []byte{0x00},
+	},
+}
+
+// AddressError describes why an address is not valid for a currency.
+type AddressError struct {
+	Currency Currency
+	Address  string
+	Reason   string
+}
+
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("Invalid %s address %q (%s)", e.Currency, e.Address, e.Reason)
+}
+
+// ValidateAddress checks that an address is well formed for the currency,
+// including its checksum, returning an *AddressError if it is not. Accepted
+// formats are:
+//
+//	BTC: Base58Check (P2PKH, P2SH) and Bech32/Bech32m segwit (bc1...)
+//	LTC: Base58Check (L..., M..., 3...) and Bech32/Bech32m segwit (ltc1...)
+//	BCH: Base58Check legacy and CashAddr, with or without the prefix
+//	ETH, ETC: 0x-prefixed hex, with an EIP-55 checksum when mixed case
+//	XRP: classic addresses (r...), without a destination tag
+func ValidateAddress(currency Currency, address string) error {
+	format, ok := addressFormats[currency]
+	if !ok {
+		return &AddressError{Currency: currency, Address: address, Reason: "Currency cannot be withdrawn to an address"}
+	}
+
+	if address == "" {
+		return &AddressError{Currency: currency, Address: address, Reason: "Address is empty"}
+	}
+
+	var reason string
+	switch {
+	case format.ethereum:
+		reason = validateEthereumAddress(address)
+
+	case format.bech32 != "" && strings.HasPrefix(strings.ToLower(address), format.bech32+"1"):
+		reason = validateSegwitAddress(format.bech32, address)
+
+	case format.cashAddr && (strings.HasPrefix(strings.ToLower(address), cashAddrPrefix+":") || strings.ContainsAny(address[:1], "qpQP")):
+		reason = validateCashAddr(address)
+
+	default:
+		reason = validateBase58Check(format.alphabet, format.versions, address)
+	}
+
+	if reason != "" {
+		return &AddressError{Currency: currency, Address: address, Reason: reason}
+	}
+
+	return nil
+}
+
+// ValidateDestinationTag checks that an XRP destination tag is an unsigned
+// 32-bit integer.
+func ValidateDestinationTag(tag string) error {
+	if _, err := strconv.ParseUint(tag, 10, 32); err != nil {
+		return &AddressError{Currency: CurrencyRipple, Address: tag, Reason: "Destination tag must be a whole number from 0 to 4294967295"}
+	}
+
+	return nil
+}
+
+// validateBase58Check validates a Base58Check address of 21 bytes (a version
+// byte and 20 byte hash) with one of the version bytes.
+func validateBase58Check(alphabet string, versions []byte, address string) string {
+	decoded, reason := base58Decode(alphabet, address)
+	if reason != "" {
+		return reason
+	}
+
+	if len(decoded) != 25 {
+		return fmt.Sprintf("Decoded length is %d bytes, expected 25", len(decoded))
+	}
+
+	payload, checksum := decoded[:21], decoded[21:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return "Checksum does not match"
+	}
+
+	if bytes.IndexByte(versions, payload[0]) < 0 {
+		return fmt.Sprintf("Version byte 0x%02x is not valid for this currency", payload[0])
+	}
+
+	return ""
+}
+
+// base58Decode decodes a Base58 string in the provided alphabet.
+func base58Decode(alphabet, s string) ([]byte, string) {
+	out := []byte{}
+	for i := 0; i < len(s); i++ {
+		digit := strings.IndexByte(alphabet, s[i])
+		if digit < 0 {
+			return nil, fmt.Sprintf("Invalid character %q at position %d", s[i], i)
+		}
+
+		carry := digit
+		for j := len(out) - 1; j >= 0; j-- {
+			carry += int(out[j]) * 58
+			out[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			out = append([]byte{byte(carry)}, out...)
+			carry >>= 8
+		}
+	}
+
+	// Leading zero digits represent leading zero bytes.
+	for i := 0; i < len(s) && s[i] == alphabet[0]; i++ {
+		out = append([]byte{0}, out...)
+	}
+
+	return out, ""
+}
+
+// validateSegwitAddress validates a Bech32 (witness version 0) or Bech32m
+// (witness versions 1 to 16) segwit address with the human readable part.
+func validateSegwitAddress(hrp, address string) string {
+	if len(address) > 90 {
+		return "Address is longer than 90 characters"
+	}
+
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return "Address mixes upper and lower case"
+	}
+	address = strings.ToLower(address)
+
+	sep := strings.LastIndexByte(address, '1')
+	if address[:sep] != hrp {
+		return fmt.Sprintf("Prefix should be %q", hrp)
+	}
+
+	data, reason := bech32Values(address[sep+1:], sep+1)
+	if reason != "" {
+		return reason
+	}
+
+	if len(data) < 7 {
+		return "Address is too short"
+	}
+
+	var values []byte
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]>>5)
+	}
+	values = append(values, 0)
+	for i := 0; i < len(hrp); i++ {
+		values = append(values, hrp[i]&31)
+	}
+	values = append(values, data...)
+
+	version := data[0]
+	expected := uint32(bech32Const)
+	if version > 0 {
+		expected = bech32mConst
+	}
+	if bech32Polymod(values) != expected {
+		return "Checksum does not match"
+	}
+
+	if version > 16 {
+		return fmt.Sprintf("Witness version %d is not valid", version)
+	}
+
+	program, ok := convertBits(data[1:len(data)-6], 5, 8, false)
+	if !ok {
+		return "Witness program is not valid"
+	}
+
+	if len(program) < 2 || len(program) > 40 {
+		return fmt.Sprintf("Witness program length %d is not valid", len(program))
+	}
+
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return fmt.Sprintf("Witness program length %d is not valid for version 0", len(program))
+	}
+
+	return ""
+}
+
+// bech32Polymod computes the Bech32 checksum of the values.
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+
+	return chk
+}
+
+// validateCashAddr validates a Bitcoin Cash CashAddr address, with or without
+// its prefix.
+func validateCashAddr(address string) string {
+	if strings.ToLower(address) != address && strings.ToUpper(address) != address {
+		return "Address mixes upper and lower case"
+	}
+
+	lower := strings.ToLower(address)
+	payload := strings.TrimPrefix(lower, cashAddrPrefix+":")
+
+	data, reason := bech32Values(payload, len(lower)-len(payload))
+	if reason != "" {
+		return reason
+	}
+
+	if len(data) <= 8 {
+		return "Address is too short"
+	}
+
+	var values []byte
+	for i := 0; i < len(cashAddrPrefix); i++ {
+		values = append(values, cashAddrPrefix[i]&31)
+	}
+	values = append(values, 0)
+	values = append(values, data...)
+
+	if cashAddrPolymod(values) != 0 {
+		return "Checksum does not match"
+	}
+
+	decoded, ok := convertBits(data[:len(data)-8], 5, 8, false)
+	if !ok || len(decoded) == 0 {
+		return "Payload is not valid"
+	}
+
+	version := decoded[0]
+	if version&0x80 != 0 {
+		return "Version byte is not valid"
+	}
+
+	if kind := version >> 3 & 0x0f; kind != 0 && kind != 1 {
+		return fmt.Sprintf("Address type %d is not valid", kind)
+	}
+
+	sizes := [8]int{20, 24, 28, 32, 40, 48, 56, 64}
+	if size := sizes[version&0x07]; len(decoded)-1 != size {
+		return fmt.Sprintf("Hash length %d does not match the version byte", len(decoded)-1)
+	}
+
+	return ""
+}
+
+// cashAddrPolymod computes the CashAddr checksum of the values.
+func cashAddrPolymod(values []byte) uint64 {
+	generator := [5]uint64{0x98f2bc8e61, 0x79b76d99e2, 0xf33e5fb3c4, 0xae2eabe2a8, 0x1e4f43e470}
+
+	c := uint64(1)
+	for _, v := range values {
+		top := c >> 35
+		c = (c&0x07ffffffff)<<5 ^ uint64(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				c ^= generator[i]
+			}
+		}
+	}
+
+	return c ^ 1
+}
+
+// bech32Values converts Bech32 characters to their 5-bit values. Positions in
+// errors are reported from the start of the address, of which s is the part
+// from offset onwards.
+func bech32Values(s string, offset int) ([]byte, string) {
+	values := make([]byte, len(s))
+	for i := 0; i < len(s); i++ {
+		v := strings.IndexByte(bech32Charset, s[i])
+		if v < 0 {
+			return nil, fmt.Sprintf("Invalid character %q at position %d", s[i], offset+i)
+		}
+		values[i] = byte(v)
+	}
+
+	return values, ""
+}
+
+// convertBits regroups data from groups of "from" bits into groups of "to"
+// bits. Without padding, any leftover bits must be zero and fewer than "from".
+func convertBits(data []byte, from, to uint, pad bool) ([]byte, bool) {
+	var (
+		acc  uint
+		bits uint
+		out  []byte
+	)
+	maxv := uint(1)<<to - 1
+
+	for _, v := range data {
+		if uint(v)>>from != 0 {
+			return nil, false
+		}
+
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(to-bits)&maxv))
+		}
+	} else if bits >= from || acc<<(to-bits)&maxv != 0 {
+		return nil, false
+	}
+
+	return out, true
+}
+
+// validateEthereumAddress validates a 0x-prefixed Ethereum address, checking
+// the EIP-55 checksum when the address uses mixed case.
+func validateEthereumAddress(address string) string {
+	if !strings.HasPrefix(address, "0x") {
+		return "Address should start with 0x"
+	}
+
+	hexPart := address[2:]
+	if len(hexPart) != 40 {
+		return fmt.Sprintf("Address has %d hex digits, expected 40", len(hexPart))
+	}
+
+	if _, err := hex.DecodeString(hexPart); err != nil {
+		return "Address is not hexadecimal"
+	}
+
+	if hexPart == strings.ToLower(hexPart) || hexPart == strings.ToUpper(hexPart) {
+		// Single case addresses carry no checksum.
+		return ""
+	}
+
+	hash := keccak256([]byte(strings.ToLower(hexPart)))
+	for i := 0; i < len(hexPart); i++ {
+		ch := hexPart[i]
+		if ch > '9' {
+			nibble := hash[i/2] >> 4
+			if i%2 == 1 {
+				nibble = hash[i/2] & 0x0f
+			}
+
+			upper := ch >= 'A' && ch <= 'F'
+			if upper != (nibble >= 8) {
+				return fmt.Sprintf("EIP-55 checksum does not match at position %d", i+2)
+			}
+		}
+	}
+
+	return ""
+}
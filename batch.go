@@ -0,0 +1,177 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// CancelAll cancels every open order in a market, or only those on one side
+// when side is not empty. Open orders are found with OrderOpen and cancelled in
+// batches with OrderCancel. The results of every cancellation are returned,
+// along with an error if any of them failed.
+func (c *Client) CancelAll(currency Currency, instrument Instrument, side OrderSide) ([]OrderCancelData, error) {
+	var ids []OrderID
+
+	it := c.OrderOpenAll(currency, instrument, 0)
+	for it.Next() {
+		o := it.Order()
+		if side == "" || o.OrderSide == side {
+			ids = append(ids, o.OrderID)
+		}
+	}
+	if err := it.Err(); err != nil {
+		return nil, err
+	}
+
+	return c.cancelBatch(ids)
+}
+
+// cancelBatch cancels orders in batches of orderBatchSize.
+func (c *Client) cancelBatch(ids []OrderID) ([]OrderCancelData, error) {
+	var (
+		results []OrderCancelData
+		failed  int
+	)
+	for start := 0; start < len(ids); start += orderBatchSize {
+		end := start + orderBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		ocr, err := c.OrderCancel(ids[start:end]...)
+		if err != nil {
+			return results, err
+		}
+
+		if !ocr.Success {
+			return results, fmt.Errorf("Failed to cancel orders (%d: %s)", ocr.ErrorCode, ocr.ErrorMessage)
+		}
+
+		for _, r := range ocr.Responses {
+			if !r.Success {
+				failed++
+			}
+			results = append(results, r)
+		}
+	}
+
+	if failed > 0 {
+		return results, fmt.Errorf("Failed to cancel %d of %d orders", failed, len(ids))
+	}
+
+	return results, nil
+}
+
+// LimitGrid returns limit orders of equal volume spread evenly across the
+// prices from "from" to "to" inclusive, such as a ladder of bids below the
+// market. AUD prices are rounded to whole cents, down for bids and up for asks.
+func LimitGrid(currency Currency, instrument Instrument, side OrderSide, from, to AmountWhole, levels int, volume AmountWhole) []OrderCreateRequest {
+	if levels < 1 {
+		return nil
+	}
+
+	grid := make([]OrderCreateRequest, levels)
+	for i := range grid {
+		price := from
+		if levels > 1 {
+			price = from + (to-from)*AmountWhole(i)/AmountWhole(levels-1)
+		}
+
+		if currency == CurrencyAUD {
+			price = roundAUDPrice(price, side)
+		}
+
+		grid[i] = OrderCreateRequest{
+			Currency:   currency,
+			Instrument: instrument,
+			Price:      price,
+			Volume:     volume,
+			OrderSide:  side,
+			OrderType:  Limit,
+		}
+	}
+
+	return grid
+}
+
+// BatchOrderResult is the outcome of placing a single order in a batch.
+type BatchOrderResult struct {
+	Request  OrderCreateRequest
+	Response *OrderCreateResponse
+	Err      error
+}
+
+// BatchResult is the outcome of PlaceBatch.
+type BatchResult struct {
+	Results    []BatchOrderResult
+	Placed     []OrderID
+	RolledBack []OrderID
+}
+
+// BatchError is returned by PlaceBatch when some of the orders could not be
+// placed.
+type BatchError struct {
+	Failed      int
+	Total       int
+	RollbackErr error
+}
+
+func (e *BatchError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("Failed to place %d of %d orders, and rollback failed (%s)", e.Failed, e.Total, e.RollbackErr.Error())
+	}
+
+	return fmt.Sprintf("Failed to place %d of %d orders", e.Failed, e.Total)
+}
+
+// PlaceBatch places each of the orders in turn, recording the outcome of each.
+// If any order fails a *BatchError is returned, and when rollback is true every
+// order which was placed is then cancelled. The result is always returned, so
+// that partial failures can be inspected.
+func (c *Client) PlaceBatch(orders []OrderCreateRequest, rollback bool) (*BatchResult, error) {
+	if len(orders) == 0 {
+		return nil, errors.New("No orders provided")
+	}
+
+	res := &BatchResult{
+		Results: make([]BatchOrderResult, len(orders)),
+	}
+
+	failed := 0
+	for i, o := range orders {
+		res.Results[i].Request = o
+
+		ocr, err := c.OrderCreate(o.Currency, o.Instrument, o.Price, o.Volume, o.OrderSide, o.OrderType, o.ClientRequestID)
+		if err == nil && !ocr.Success {
+			err = fmt.Errorf("Failed to place order (%d: %s)", ocr.ErrorCode, ocr.ErrorMessage)
+		}
+
+		res.Results[i].Response = ocr
+		res.Results[i].Err = err
+
+		if err != nil {
+			failed++
+			continue
+		}
+
+		res.Placed = append(res.Placed, ocr.ID)
+	}
+
+	if failed == 0 {
+		return res, nil
+	}
+
+	berr := &BatchError{Failed: failed, Total: len(orders)}
+
+	if rollback && len(res.Placed) > 0 {
+		cancelled, err := c.cancelBatch(res.Placed)
+		for _, r := range cancelled {
+			if r.Success {
+				res.RolledBack = append(res.RolledBack, r.ID)
+			}
+		}
+		berr.RollbackErr = err
+	}
+
+	return res, berr
+}
@@ -2,8 +2,10 @@ package btcmarkets
 
 import (
 	"crypto/hmac"
+	"crypto/rand"
 	"crypto/sha512"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -35,9 +37,10 @@ func init() {
 // API endpoints, and 10 calls per 10 seconds on others. It is concurrency safe.
 // (documented at https://github.com/BTCMarkets/API/wiki/faq)
 type Client struct {
-	apikey string
-	secret []byte
-	rate10 *rateLimit
+	apikey  string
+	secret  []byte
+	rate10  *rateLimit
+	baseURL string
 }
 
 // NewClient constructs a new Client for communicating with the BTC Markets API.
@@ -58,9 +61,10 @@ func NewClient(key, secret string) (*Client, error) {
 	rate10.Start(time.Second, rateLimit10)
 
 	return &Client{
-		apikey: key,
-		secret: binSecret,
-		rate10: rate10,
+		apikey:  key,
+		secret:  binSecret,
+		rate10:  rate10,
+		baseURL: BaseURL,
 	}, nil
 }
 
@@ -105,7 +109,7 @@ func (c *Client) doRequest(req *http.Request, body string, v interface{}, rateLi
 
 // Get handles a GET request to any BTC Markest API endpoint.
 func (c *Client) Get(path string, v interface{}, rateLimit RateLimitValue) error {
-	req, _, err := NewRequest("GET", BaseURL+path, nil)
+	req, _, err := NewRequest("GET", c.baseURL+path, nil)
 	if err != nil {
 		return err
 	}
@@ -120,7 +124,7 @@ func (c *Client) Get(path string, v interface{}, rateLimit RateLimitValue) error
 
 // Post handles a POST request to any BTC Markest API endpoint.
 func (c *Client) Post(path string, data interface{}, v interface{}, rateLimit RateLimitValue) error {
-	req, body, err := NewRequest("POST", BaseURL+path, data)
+	req, body, err := NewRequest("POST", c.baseURL+path, data)
 	if err != nil {
 		return err
 	}
@@ -163,6 +167,17 @@ func (c *Client) String() string {
 	return fmt.Sprintf("Client:{%s}", c.apikey)
 }
 
+// randomID returns a random identifier for objects held client-side, such as
+// triggers and pending withdrawals.
+func randomID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", fmt.Errorf("Failed to generate ID (%s)", err.Error())
+	}
+
+	return hex.EncodeToString(id), nil
+}
+
 // Limit10 performs rate limiting of 10x / 10secs
 func (c *Client) Limit10() error {
 	return c.rate10.Limit()
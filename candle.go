@@ -0,0 +1,318 @@
+package btcmarkets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Common candle intervals.
+const (
+	CandleInterval1m = time.Minute
+	CandleInterval5m = 5 * time.Minute
+	CandleInterval1h = time.Hour
+	CandleInterval1d = 24 * time.Hour
+)
+
+// Candle is a single OHLCV (open, high, low, close, volume) bar covering the
+// trades which occurred from Start up to, but not including, End.
+type Candle struct {
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Open   AmountDecimal `json:"open"`
+	High   AmountDecimal `json:"high"`
+	Low    AmountDecimal `json:"low"`
+	Close  AmountDecimal `json:"close"`
+	Volume AmountDecimal `json:"volume"`
+	Trades int           `json:"trades"`
+
+	openID  TradeID
+	closeID TradeID
+}
+
+// String is a helper method for displaying a candle in human-readable format.
+func (cd *Candle) String() string {
+	return fmt.Sprintf("%s O:%f H:%f L:%f C:%f V:%f", cd.Start.Format(time.RFC3339), cd.Open, cd.High, cd.Low, cd.Close, cd.Volume)
+}
+
+// CandleBuilder aggregates MarketTradeDataItem trades into candles of a fixed
+// interval. Trades may be added in any order (such as when polling live trades
+// and backfilling history at the same time), and trades which have already been
+// added are ignored. It is concurrency safe.
+//
+// Candle boundaries are aligned to the wall clock of the builder's location, so
+// daily candles begin at local midnight.
+type CandleBuilder struct {
+	// FillGaps, when true, causes Candles and Flush to include empty candles
+	// for intervals with no trades. Empty candles carry the previous close as
+	// their open, high, low and close, with zero volume.
+	FillGaps bool
+
+	interval time.Duration
+	location *time.Location
+
+	mu      sync.Mutex
+	candles map[int64]*Candle
+	seen    map[TradeID]struct{}
+	flushed time.Time
+	last    *Candle
+	dropped int
+}
+
+// NewCandleBuilder constructs a new CandleBuilder for the provided interval.
+// Boundaries are aligned in the provided location, or UTC when nil.
+func NewCandleBuilder(interval time.Duration, location *time.Location) (*CandleBuilder, error) {
+	if interval < time.Second {
+		return nil, errors.New("Candle interval must be at least one second")
+	}
+
+	if interval > CandleInterval1d && interval%CandleInterval1d != 0 {
+		return nil, errors.New("Candle intervals longer than a day must be a whole number of days")
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	return &CandleBuilder{
+		interval: interval,
+		location: location,
+		candles:  make(map[int64]*Candle),
+		seen:     make(map[TradeID]struct{}),
+	}, nil
+}
+
+// Add adds trades to the builder. Trades belonging to candles which have
+// already been flushed are late, and are counted by Dropped rather than added.
+func (b *CandleBuilder) Add(trades ...MarketTradeDataItem) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, t := range trades {
+		if _, ok := b.seen[t.TradeID]; ok {
+			continue
+		}
+
+		at := time.Unix(t.Timestamp, 0)
+		start := b.align(at)
+
+		if !b.flushed.IsZero() && start.Before(b.flushed) {
+			b.dropped++
+			continue
+		}
+
+		b.seen[t.TradeID] = struct{}{}
+
+		cd, ok := b.candles[start.Unix()]
+		if !ok {
+			cd = &Candle{
+				Start:   start,
+				End:     b.next(start),
+				Open:    t.Price,
+				High:    t.Price,
+				Low:     t.Price,
+				Close:   t.Price,
+				openID:  t.TradeID,
+				closeID: t.TradeID,
+			}
+			b.candles[start.Unix()] = cd
+		}
+
+		// Trade IDs increase over time, so they order trades within a candle
+		// even when they arrive out of order.
+		if t.TradeID < cd.openID {
+			cd.Open = t.Price
+			cd.openID = t.TradeID
+		}
+		if t.TradeID > cd.closeID {
+			cd.Close = t.Price
+			cd.closeID = t.TradeID
+		}
+		if t.Price > cd.High {
+			cd.High = t.Price
+		}
+		if t.Price < cd.Low {
+			cd.Low = t.Price
+		}
+
+		cd.Volume += t.Amount
+		cd.Trades++
+	}
+}
+
+// Candles returns all candles currently held by the builder, in time order.
+// The most recent candle may still be receiving trades.
+func (b *CandleBuilder) Candles() []Candle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.collect(time.Time{})
+}
+
+// Flush removes and returns, in time order, every candle which ends at or
+// before the provided time. Flushed candles are final, so any trade arriving
+// later for those intervals is dropped.
+func (b *CandleBuilder) Flush(before time.Time) []Candle {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	out := b.collect(before)
+	for _, cd := range out {
+		delete(b.candles, cd.Start.Unix())
+	}
+
+	if len(out) > 0 {
+		last := out[len(out)-1]
+		b.last = &last
+		b.flushed = last.End
+	}
+
+	// Trades for flushed candles are rejected by time, so their IDs need not
+	// be remembered.
+	oldest := b.oldestID()
+	for id := range b.seen {
+		if len(b.candles) == 0 || id < oldest {
+			delete(b.seen, id)
+		}
+	}
+
+	return out
+}
+
+// Dropped returns the number of late trades which arrived after their candle
+// had been flushed.
+func (b *CandleBuilder) Dropped() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.dropped
+}
+
+// collect returns the held candles which end at or before the provided time
+// (or every candle when zero), filling gaps when configured.
+func (b *CandleBuilder) collect(before time.Time) []Candle {
+	starts := make([]int64, 0, len(b.candles))
+	for start, cd := range b.candles {
+		if before.IsZero() || !cd.End.After(before) {
+			starts = append(starts, start)
+		}
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	out := make([]Candle, 0, len(starts))
+	prev := b.last
+	for _, start := range starts {
+		cd := *b.candles[start]
+
+		if b.FillGaps && prev != nil {
+			for gap := prev.End; gap.Before(cd.Start); gap = b.next(gap) {
+				out = append(out, Candle{
+					Start: gap,
+					End:   b.next(gap),
+					Open:  prev.Close,
+					High:  prev.Close,
+					Low:   prev.Close,
+					Close: prev.Close,
+				})
+			}
+		}
+
+		out = append(out, cd)
+		prev = &out[len(out)-1]
+	}
+
+	return out
+}
+
+// oldestID returns the lowest opening trade ID of the held candles.
+func (b *CandleBuilder) oldestID() TradeID {
+	var oldest TradeID
+	for _, cd := range b.candles {
+		if oldest == 0 || cd.openID < oldest {
+			oldest = cd.openID
+		}
+	}
+
+	return oldest
+}
+
+// align returns the start of the candle containing the provided time.
+func (b *CandleBuilder) align(t time.Time) time.Time {
+	local := t.In(b.location)
+
+	if b.interval%CandleInterval1d == 0 {
+		days := int(b.interval / CandleInterval1d)
+		start := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, b.location)
+
+		// Multi-day candles are counted from the Unix epoch in local days.
+		epoch := time.Date(1970, 1, 1, 0, 0, 0, 0, b.location)
+		offset := int(start.Sub(epoch).Hours()+12) / 24 % days
+
+		return start.AddDate(0, 0, -offset)
+	}
+
+	_, zoneOffset := local.Zone()
+	secs := int64(b.interval / time.Second)
+	unix := t.Unix() + int64(zoneOffset)
+	unix -= ((unix % secs) + secs) % secs
+
+	return time.Unix(unix-int64(zoneOffset), 0).In(b.location)
+}
+
+// next returns the start of the candle following the one starting at start.
+func (b *CandleBuilder) next(start time.Time) time.Time {
+	if b.interval%CandleInterval1d == 0 {
+		return start.AddDate(0, 0, int(b.interval/CandleInterval1d))
+	}
+
+	return start.Add(b.interval)
+}
+
+// WriteCandlesCSV writes candles to w as CSV, with a header row.
+func WriteCandlesCSV(w io.Writer, candles []Candle) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{"start", "end", "open", "high", "low", "close", "volume", "trades"})
+	if err != nil {
+		return fmt.Errorf("Failed to write candles (%s)", err.Error())
+	}
+
+	for _, cd := range candles {
+		err = cw.Write([]string{
+			cd.Start.Format(time.RFC3339),
+			cd.End.Format(time.RFC3339),
+			strconv.FormatFloat(float64(cd.Open), 'f', -1, 64),
+			strconv.FormatFloat(float64(cd.High), 'f', -1, 64),
+			strconv.FormatFloat(float64(cd.Low), 'f', -1, 64),
+			strconv.FormatFloat(float64(cd.Close), 'f', -1, 64),
+			strconv.FormatFloat(float64(cd.Volume), 'f', -1, 64),
+			strconv.Itoa(cd.Trades),
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to write candles (%s)", err.Error())
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return fmt.Errorf("Failed to write candles (%s)", err.Error())
+	}
+
+	return nil
+}
+
+// WriteCandlesJSON writes candles to w as a JSON array.
+func WriteCandlesJSON(w io.Writer, candles []Candle) error {
+	err := json.NewEncoder(w).Encode(candles)
+	if err != nil {
+		return fmt.Errorf("Failed to write candles (%s)", err.Error())
+	}
+
+	return nil
+}
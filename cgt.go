@@ -0,0 +1,312 @@
+package btcmarkets
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// CGTEvent is a single capital gains tax event, being the disposal of part or
+// all of a parcel of an instrument. Amounts are in AUD.
+type CGTEvent struct {
+	FinancialYear string      `json:"financialYear"`
+	Instrument    Instrument  `json:"instrument"`
+	BuyTradeID    TradeID     `json:"buyTradeId"`
+	SellTradeID   TradeID     `json:"sellTradeId"`
+	Acquired      time.Time   `json:"acquired"`
+	Disposed      time.Time   `json:"disposed"`
+	HoldingDays   int         `json:"holdingDays"`
+	Volume        AmountWhole `json:"volume"`
+	CostBase      AmountWhole `json:"costBase"`
+	Proceeds      AmountWhole `json:"proceeds"`
+	Gain          AmountWhole `json:"gain"`
+
+	// DiscountEligible is true when the parcel was held for at least 12
+	// months, excluding the days of acquisition and disposal, making a gain
+	// eligible for the CGT discount.
+	DiscountEligible bool `json:"discountEligible"`
+}
+
+// CGTYear is the summary of the CGT events of a financial year (1 July to
+// 30 June).
+type CGTYear struct {
+	FinancialYear string     `json:"financialYear"`
+	Events        []CGTEvent `json:"events"`
+
+	// Gains is the total of every capital gain, of which DiscountableGains
+	// were eligible for the discount.
+	Gains             AmountWhole `json:"gains"`
+	DiscountableGains AmountWhole `json:"discountableGains"`
+
+	// Losses is the total of every capital loss, as a positive amount.
+	Losses AmountWhole `json:"losses"`
+
+	// NetCapitalGain is the gain after applying the year's losses (first to
+	// gains not eligible for the discount) and then the 50% discount. Losses
+	// carried forward from earlier years are not included.
+	NetCapitalGain AmountWhole `json:"netCapitalGain"`
+
+	// LossCarriedForward is the loss left over when losses exceed gains.
+	LossCarriedForward AmountWhole `json:"lossCarriedForward"`
+}
+
+// CGTReport is an Australian capital gains tax event ledger, grouped by
+// financial year. Skipped holds the fills of non-AUD markets which could not
+// be included because no CGTPriceFunc was provided.
+type CGTReport struct {
+	Method  CostMethod `json:"method"`
+	Years   []CGTYear  `json:"years"`
+	Skipped []Fill     `json:"skipped,omitempty"`
+}
+
+// CGTPriceFunc returns the AUD price of one unit of a currency at a time, used
+// to value trades between two crypto currencies (such as ETH/BTC).
+type CGTPriceFunc func(currency Currency, at time.Time) (AmountWhole, error)
+
+// NewCGTReport builds a CGT report from the account's complete trade history,
+// matching disposals to parcels using the cost method. Financial years and
+// holding periods are measured in the provided location, which should normally
+// be the taxpayer's Australian time zone, or UTC when nil.
+//
+// A trade in a non-AUD market disposes of one crypto currency and acquires
+// another, so with a price function it is valued in AUD and counted as both.
+// Without one, such trades are left out and listed in the report's Skipped
+// fills; leaving them out may make later sales exceed the holding, which is an
+// error.
+//
+// Opening parcels are holdings acquired outside the fills, such as coins
+// transferred in from another exchange, with their cost base in AUD. Their
+// pairs must be AUD markets and their Acquired dates are used for the discount.
+//
+// CostAverage is not accepted, as each parcel's acquisition date is needed to
+// decide whether a gain is eligible for the discount.
+func NewCGTReport(fills []Fill, method CostMethod, location *time.Location, price CGTPriceFunc, opening ...Lot) (*CGTReport, error) {
+	if method == CostAverage {
+		return nil, errors.New("CGT reports require parcels to be identified, so cannot use average cost")
+	}
+
+	for _, lot := range opening {
+		if lot.Pair.Currency != CurrencyAUD {
+			return nil, fmt.Errorf("Opening parcel of %s must have its cost base in AUD, not %s", lot.Pair.Instrument, lot.Pair.Currency)
+		}
+	}
+
+	if location == nil {
+		location = time.UTC
+	}
+
+	report := &CGTReport{Method: method}
+
+	aud := make([]Fill, 0, len(fills))
+	for _, f := range fills {
+		if f.Pair.Currency == CurrencyAUD {
+			aud = append(aud, f)
+			continue
+		}
+
+		if price == nil {
+			report.Skipped = append(report.Skipped, f)
+			continue
+		}
+
+		legs, err := audFills(f, price)
+		if err != nil {
+			return nil, err
+		}
+		aud = append(aud, legs...)
+	}
+
+	disposals, _, err := MatchLots(aud, method, opening...)
+	if err != nil {
+		return nil, err
+	}
+
+	years := make(map[string]*CGTYear)
+	for _, d := range disposals {
+		acquired := dateIn(d.Acquired, location)
+		disposed := dateIn(d.Disposed, location)
+
+		ev := CGTEvent{
+			FinancialYear:    financialYear(disposed),
+			Instrument:       d.Pair.Instrument,
+			BuyTradeID:       d.BuyTradeID,
+			SellTradeID:      d.SellTradeID,
+			Acquired:         d.Acquired.In(location),
+			Disposed:         d.Disposed.In(location),
+			HoldingDays:      int(disposed.Sub(acquired).Hours()+12) / 24,
+			Volume:           d.Volume,
+			CostBase:         d.CostBase,
+			Proceeds:         d.Proceeds,
+			Gain:             d.Gain(),
+			DiscountEligible: disposed.After(acquired.AddDate(1, 0, 0)),
+		}
+
+		y, ok := years[ev.FinancialYear]
+		if !ok {
+			y = &CGTYear{FinancialYear: ev.FinancialYear}
+			years[ev.FinancialYear] = y
+		}
+
+		y.Events = append(y.Events, ev)
+		switch {
+		case ev.Gain > 0:
+			y.Gains += ev.Gain
+			if ev.DiscountEligible {
+				y.DiscountableGains += ev.Gain
+			}
+		case ev.Gain < 0:
+			y.Losses -= ev.Gain
+		}
+	}
+
+	for _, y := range years {
+		// Losses are applied to gains which cannot be discounted first, as
+		// that gives the lowest net capital gain.
+		undiscounted := y.Gains - y.DiscountableGains
+		discountable := y.DiscountableGains
+		losses := y.Losses
+
+		for _, gains := range []*AmountWhole{&undiscounted, &discountable} {
+			applied := losses
+			if applied > *gains {
+				applied = *gains
+			}
+
+			*gains -= applied
+			losses -= applied
+		}
+
+		y.NetCapitalGain = undiscounted + discountable/2
+		y.LossCarriedForward = losses
+
+		report.Years = append(report.Years, *y)
+	}
+
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].FinancialYear < report.Years[j].FinancialYear })
+
+	return report, nil
+}
+
+// audFills converts a fill in a crypto currency market into the equivalent
+// AUD fills of its two currencies, valuing the quote currency with the price
+// function. A purchase acquires the instrument and disposes of the quote
+// currency paid, including the fee; a sale disposes of the instrument and
+// acquires the quote currency received, net of the fee.
+func audFills(f Fill, price CGTPriceFunc) ([]Fill, error) {
+	rate, err := price(f.Pair.Currency, f.Time)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to value trade %d in AUD (%s)", f.TradeID, err.Error())
+	}
+	if rate <= 0 {
+		return nil, fmt.Errorf("Failed to value trade %d in AUD (no %s price)", f.TradeID, f.Pair.Currency)
+	}
+
+	instrument := f
+	instrument.Pair = MarketPair{Instrument: f.Pair.Instrument, Currency: CurrencyAUD}
+	instrument.Price = f.Price.Mul(rate)
+	instrument.Fee = f.Fee.Mul(rate)
+
+	quote := Fill{
+		Pair:    MarketPair{Instrument: Instrument(f.Pair.Currency), Currency: CurrencyAUD},
+		TradeID: f.TradeID,
+		OrderID: f.OrderID,
+		Time:    f.Time,
+		Price:   rate,
+	}
+
+	switch f.Side {
+	case Bid:
+		quote.Side = Ask
+		quote.Volume = f.Price.Mul(f.Volume) + f.Fee
+
+		// The quote currency is disposed of before the instrument is
+		// acquired with it.
+		return []Fill{quote, instrument}, nil
+
+	case Ask:
+		quote.Side = Bid
+		quote.Volume = f.Price.Mul(f.Volume) - f.Fee
+
+		return []Fill{instrument, quote}, nil
+	}
+
+	return nil, fmt.Errorf("Trade %d has unknown side %q", f.TradeID, f.Side)
+}
+
+// dateIn returns midnight of the day of the time in the location.
+func dateIn(t time.Time, location *time.Location) time.Time {
+	t = t.In(location)
+
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, location)
+}
+
+// financialYear returns the Australian financial year containing the date, in
+// the form "2017-18".
+func financialYear(date time.Time) string {
+	start := date.Year()
+	if date.Month() < time.July {
+		start--
+	}
+
+	return fmt.Sprintf("%d-%02d", start, (start+1)%100)
+}
+
+// WriteCSV writes every CGT event to w as CSV, with a header row. Amounts are
+// written as exact decimals.
+func (r *CGTReport) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+
+	err := cw.Write([]string{
+		"financial_year", "instrument", "buy_trade_id", "sell_trade_id", "acquired", "disposed",
+		"holding_days", "volume", "cost_base", "proceeds", "gain", "discount_eligible",
+	})
+	if err != nil {
+		return fmt.Errorf("Failed to write CGT report (%s)", err.Error())
+	}
+
+	for _, y := range r.Years {
+		for _, ev := range y.Events {
+			err = cw.Write([]string{
+				ev.FinancialYear,
+				string(ev.Instrument),
+				strconv.FormatInt(int64(ev.BuyTradeID), 10),
+				strconv.FormatInt(int64(ev.SellTradeID), 10),
+				ev.Acquired.Format(time.RFC3339),
+				ev.Disposed.Format(time.RFC3339),
+				strconv.Itoa(ev.HoldingDays),
+				ev.Volume.DecimalString(),
+				ev.CostBase.DecimalString(),
+				ev.Proceeds.DecimalString(),
+				ev.Gain.DecimalString(),
+				strconv.FormatBool(ev.DiscountEligible),
+			})
+			if err != nil {
+				return fmt.Errorf("Failed to write CGT report (%s)", err.Error())
+			}
+		}
+	}
+
+	cw.Flush()
+	if err = cw.Error(); err != nil {
+		return fmt.Errorf("Failed to write CGT report (%s)", err.Error())
+	}
+
+	return nil
+}
+
+// WriteJSON writes the report to w as JSON. Amounts are in AmountWhole units.
+func (r *CGTReport) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+
+	if err := enc.Encode(r); err != nil {
+		return fmt.Errorf("Failed to write CGT report (%s)", err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+package btcmarkets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNewCGTReportOpeningParcels(t *testing.T) {
+	pair := MarketPair{Instrument: InstrumentBitcoin, Currency: CurrencyAUD}
+	sold := time.Date(2018, time.March, 1, 0, 0, 0, 0, time.UTC)
+
+	fills := []Fill{{Pair: pair, Side: Ask, TradeID: 9, Time: sold, Price: 1500000000000, Volume: 100000000}}
+
+	if _, err := NewCGTReport(fills, CostFIFO, nil, nil); err == nil {
+		t.Fatal("NewCGTReport error = nil, want an error for a sale without a parcel")
+	}
+
+	if _, err := NewCGTReport(fills, CostAverage, nil, nil); err == nil {
+		t.Fatal("NewCGTReport error = nil, want an error for average cost")
+	}
+
+	// Transferred in well over a year before the sale.
+	parcel := Lot{Pair: pair, Acquired: time.Date(2016, time.January, 4, 0, 0, 0, 0, time.UTC), Volume: 100000000, Cost: 50000000000}
+
+	report, err := NewCGTReport(fills, CostFIFO, nil, nil, parcel)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(report.Years) != 1 || len(report.Years[0].Events) != 1 {
+		t.Fatalf("report = %+v, want one event", report)
+	}
+
+	y := report.Years[0]
+	if y.FinancialYear != "2017-18" {
+		t.Errorf("FinancialYear = %q, want 2017-18", y.FinancialYear)
+	}
+	if ev := y.Events[0]; !ev.DiscountEligible || ev.Gain != 1450000000000 {
+		t.Errorf("event = %+v, want a discountable gain of 1450000000000", ev)
+	}
+	if y.NetCapitalGain != 725000000000 {
+		t.Errorf("NetCapitalGain = %d, want 725000000000", y.NetCapitalGain)
+	}
+}
@@ -0,0 +1,166 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DeadMansSwitch cancels every open order in its markets if the application
+// stops sending heartbeats, so that a crashed bot does not leave orders on the
+// book. It is concurrency safe.
+//
+// The switch can run inside the application, with Heartbeat called from its
+// main loop, or as a separate sidecar process sharing the same API key. As a
+// sidecar it is served over HTTP (it implements http.Handler), and the
+// application calls PostHeartbeat with its address:
+//
+//	dms := btcmarkets.NewDeadMansSwitch(cl, time.Minute, markets...)
+//	dms.Start()
+//	log.Fatal(http.ListenAndServe("127.0.0.1:8421", dms))
+type DeadMansSwitch struct {
+	// OnFire, if set, is called with the outcome each time the switch fires.
+	OnFire func(cancelled []OrderCancelData, err error)
+
+	c       *Client
+	timeout time.Duration
+	markets []MarketPair
+
+	mu    sync.Mutex
+	last  time.Time
+	armed bool
+	fired time.Time
+
+	poller poller
+}
+
+// NewDeadMansSwitch constructs a new DeadMansSwitch which fires when no
+// heartbeat has been received for the timeout. It is armed by the first
+// heartbeat.
+func NewDeadMansSwitch(c *Client, timeout time.Duration, markets ...MarketPair) *DeadMansSwitch {
+	return &DeadMansSwitch{
+		c:       c,
+		timeout: timeout,
+		markets: markets,
+	}
+}
+
+// Heartbeat records that the application is alive, arming the switch.
+func (d *DeadMansSwitch) Heartbeat() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.last = time.Now()
+	d.armed = true
+}
+
+// Start begins watching for missed heartbeats in the background, checking
+// several times per timeout. It does nothing if the switch is already running.
+func (d *DeadMansSwitch) Start() {
+	check := d.timeout / 4
+	if check < time.Second {
+		check = time.Second
+	}
+
+	d.poller.start(check, false, func(<-chan struct{}) {
+		d.mu.Lock()
+		expired := d.armed && time.Since(d.last) > d.timeout
+		d.mu.Unlock()
+
+		if expired {
+			d.Fire()
+		}
+	})
+}
+
+// Stop stops watching for missed heartbeats, without cancelling any orders.
+func (d *DeadMansSwitch) Stop() {
+	d.poller.halt()
+}
+
+// Fire cancels every open order in the switch's markets immediately and
+// disarms the switch until the next heartbeat.
+func (d *DeadMansSwitch) Fire() ([]OrderCancelData, error) {
+	var (
+		cancelled []OrderCancelData
+		failed    []string
+	)
+	for _, m := range d.markets {
+		res, err := d.c.CancelAll(m.Currency, m.Instrument, "")
+		cancelled = append(cancelled, res...)
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", m, err.Error()))
+		}
+	}
+
+	var err error
+	if len(failed) > 0 {
+		err = fmt.Errorf("Failed to cancel orders in %d markets (%v)", len(failed), failed)
+	}
+
+	d.mu.Lock()
+	// Stay armed on failure so that cancelling is retried.
+	if err == nil {
+		d.armed = false
+		d.fired = time.Now()
+	}
+	onFire := d.OnFire
+	d.mu.Unlock()
+
+	if onFire != nil {
+		onFire(cancelled, err)
+	}
+
+	return cancelled, err
+}
+
+// DeadMansSwitchStatus is the state of a DeadMansSwitch as reported over HTTP.
+type DeadMansSwitchStatus struct {
+	Armed         bool      `json:"armed"`
+	LastHeartbeat time.Time `json:"lastHeartbeat"`
+	LastFired     time.Time `json:"lastFired"`
+	Timeout       string    `json:"timeout"`
+}
+
+// Status returns the current state of the switch.
+func (d *DeadMansSwitch) Status() DeadMansSwitchStatus {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return DeadMansSwitchStatus{
+		Armed:         d.armed,
+		LastHeartbeat: d.last,
+		LastFired:     d.fired,
+		Timeout:       d.timeout.String(),
+	}
+}
+
+// ServeHTTP implements http.Handler. A POST records a heartbeat, and any other
+// request reports the switch's status as JSON.
+func (d *DeadMansSwitch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == "POST" {
+		d.Heartbeat()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(d.Status())
+}
+
+// PostHeartbeat sends a heartbeat to a DeadMansSwitch served over HTTP at the
+// provided URL.
+func PostHeartbeat(url string) error {
+	resp, err := httpClient.Post(url, "application/json", nil)
+	if err != nil {
+		return fmt.Errorf("Failed to send heartbeat (%s)", err.Error())
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return errors.New("Failed to send heartbeat (" + resp.Status + ")")
+	}
+
+	return nil
+}
@@ -0,0 +1,341 @@
+package btcmarkets
+
+import (
+	_ "embed" // for the bundled BSB dataset
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// bsbBanks is the bundled dataset of BSB prefixes and the banks they belong
+// to. A BSB's first two digits (three for some institutions) identify its
+// bank, so it covers every branch of the listed banks.
+//
+//go:embed bsbbanks.csv
+var bsbBanks string
+
+// Limits of Australian bank account details, as accepted by the Bulk
+// Electronic Clearing System.
+const (
+	maxAccountNameLength   = 32
+	minAccountNumberLength = 5
+	maxAccountNumberLength = 9
+)
+
+// EFTError describes why a field of an EFTDestination is not valid.
+type EFTError struct {
+	Field  string
+	Value  string
+	Reason string
+}
+
+func (e *EFTError) Error() string {
+	return fmt.Sprintf("Invalid %s %q (%s)", e.Field, e.Value, e.Reason)
+}
+
+// BSBBank is a bank identified by a BSB prefix.
+type BSBBank struct {
+	Prefix   string
+	Mnemonic string
+	Name     string
+	Aliases  []string
+}
+
+// BSBEntry is the bank and, when known, the branch of a BSB.
+type BSBEntry struct {
+	BSB      string
+	Bank     BSBBank
+	Branch   string
+	Address  string
+	Suburb   string
+	State    string
+	Postcode string
+}
+
+// BSBDirectory looks up the bank and branch of BSBs. It always knows the
+// banks of the bundled dataset, and the branches of a directory file loaded
+// with Load. It is concurrency safe.
+type BSBDirectory struct {
+	mu       sync.RWMutex
+	banks    map[string]BSBBank
+	branches map[string]BSBEntry
+}
+
+// DefaultBSBDirectory is the directory used by EFTDestination.Validate. Load a
+// full directory file into it to validate branches as well as banks.
+var DefaultBSBDirectory = NewBSBDirectory()
+
+// NewBSBDirectory constructs a new BSBDirectory of the banks in the bundled
+// dataset.
+func NewBSBDirectory() *BSBDirectory {
+	d := &BSBDirectory{
+		banks:    make(map[string]BSBBank),
+		branches: make(map[string]BSBEntry),
+	}
+
+	records, err := csv.NewReader(strings.NewReader(bsbBanks)).ReadAll()
+	if err != nil {
+		panic("btcmarkets: bundled BSB dataset is invalid: " + err.Error())
+	}
+
+	for _, r := range records[1:] {
+		bank := BSBBank{Prefix: r[0], Mnemonic: r[1], Name: r[2]}
+		if r[3] != "" {
+			bank.Aliases = strings.Split(r[3], "|")
+		}
+		d.banks[bank.Prefix] = bank
+	}
+
+	return d
+}
+
+// LoadBSBDirectory constructs a new BSBDirectory of the bundled banks and the
+// branches in a directory file (see Load).
+func LoadBSBDirectory(r io.Reader) (*BSBDirectory, error) {
+	d := NewBSBDirectory()
+	if err := d.Load(r); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Load adds the branches in a BSB directory file, in the comma separated
+// format published by the Australian Payments Network (BSB, bank mnemonic,
+// branch name, address, suburb, state, postcode, payment flags). Rows which do
+// not start with a BSB, such as a header, are skipped.
+func (d *BSBDirectory) Load(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.LazyQuotes = true
+	cr.TrimLeadingSpace = true
+
+	branches := make(map[string]BSBEntry)
+	for {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("Failed to read BSB directory (%s)", err.Error())
+		}
+
+		bsb, ok := normaliseBSB(rec[0])
+		if !ok || len(rec) < 3 {
+			continue
+		}
+
+		for len(rec) < 7 {
+			rec = append(rec, "")
+		}
+
+		e := BSBEntry{
+			BSB:      bsb,
+			Branch:   strings.TrimSpace(rec[2]),
+			Address:  strings.TrimSpace(rec[3]),
+			Suburb:   strings.TrimSpace(rec[4]),
+			State:    strings.TrimSpace(rec[5]),
+			Postcode: strings.TrimSpace(rec[6]),
+		}
+
+		d.mu.RLock()
+		bank, known := d.bank(bsb)
+		d.mu.RUnlock()
+		if !known {
+			bank = BSBBank{Mnemonic: strings.TrimSpace(rec[1])}
+		}
+		e.Bank = bank
+
+		branches[bsb] = e
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	for bsb, e := range branches {
+		d.branches[bsb] = e
+	}
+
+	return nil
+}
+
+// Lookup returns the bank and, if a directory file has been loaded, the branch
+// of a BSB. It returns false if neither is known.
+func (d *BSBDirectory) Lookup(bsb string) (BSBEntry, bool) {
+	bsb, ok := normaliseBSB(bsb)
+	if !ok {
+		return BSBEntry{}, false
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if e, ok := d.branches[bsb]; ok {
+		return e, true
+	}
+
+	if bank, ok := d.bank(bsb); ok {
+		return BSBEntry{BSB: bsb, Bank: bank}, true
+	}
+
+	return BSBEntry{}, false
+}
+
+// HasBranches reports whether a directory file has been loaded, so that BSBs
+// not in it can be treated as unknown.
+func (d *BSBDirectory) HasBranches() bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return len(d.branches) > 0
+}
+
+// bank returns the bank of a normalised BSB, preferring the longest matching
+// prefix. It must be called with the lock held.
+func (d *BSBDirectory) bank(bsb string) (BSBBank, bool) {
+	if bank, ok := d.banks[bsb[:3]]; ok {
+		return bank, true
+	}
+
+	bank, ok := d.banks[bsb[:2]]
+
+	return bank, ok
+}
+
+// Matches reports whether a bank name refers to the bank, by its name,
+// mnemonic or an alias, ignoring case, punctuation and trailing words such as
+// "Limited".
+func (b BSBBank) Matches(name string) bool {
+	n := normaliseBankName(name)
+	if n == "" {
+		return false
+	}
+
+	for _, candidate := range append([]string{b.Name, b.Mnemonic}, b.Aliases...) {
+		c := normaliseBankName(candidate)
+		if c != "" && (n == c || strings.HasPrefix(n, c) || strings.HasPrefix(c, n) && len(n) >= 3) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// normaliseBankName lower cases a bank name, removing punctuation, spaces and
+// company suffixes.
+func normaliseBankName(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+
+	n := b.String()
+	for _, suffix := range []string{"limited", "ltd", "pty"} {
+		n = strings.TrimSuffix(n, suffix)
+	}
+
+	return n
+}
+
+// normaliseBSB returns a BSB in "NNN-NNN" form, accepting "NNN-NNN",
+// "NNN NNN" and "NNNNNN".
+func normaliseBSB(bsb string) (string, bool) {
+	bsb = strings.TrimSpace(bsb)
+	if len(bsb) == 7 && (bsb[3] == '-' || bsb[3] == ' ') {
+		bsb = bsb[:3] + bsb[4:]
+	}
+
+	if len(bsb) != 6 || !isDigits(bsb) {
+		return "", false
+	}
+
+	return bsb[:3] + "-" + bsb[3:], true
+}
+
+// isDigits reports whether a non-empty string is only the digits 0 to 9.
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EFTDestination is the Australian bank account an EFT withdrawal is paid to.
+type EFTDestination struct {
+	AccountName   string
+	AccountNumber string
+	BankName      string
+	BSB           string
+}
+
+// Validate checks the destination's structure and cross-checks its bank name
+// with DefaultBSBDirectory, returning an *EFTError if it is not valid.
+func (d EFTDestination) Validate() error {
+	return d.ValidateWith(DefaultBSBDirectory)
+}
+
+// ValidateWith checks the destination's structure: a BSB of six digits, an
+// account number of 5 to 9 digits and an account name of at most 32
+// characters. When the directory knows the BSB's bank, the bank name must
+// match it, and when the directory has branches loaded the BSB must be one of
+// them. BSBs have no check digit, so a well formed BSB may still not exist.
+func (d EFTDestination) ValidateWith(dir *BSBDirectory) error {
+	name := strings.TrimSpace(d.AccountName)
+	if name == "" {
+		return &EFTError{Field: "account name", Value: d.AccountName, Reason: "Account name is required"}
+	}
+	if len(name) > maxAccountNameLength {
+		return &EFTError{Field: "account name", Value: d.AccountName, Reason: fmt.Sprintf("Account name is longer than %d characters", maxAccountNameLength)}
+	}
+
+	number := d.accountNumber()
+	if !isDigits(number) {
+		return &EFTError{Field: "account number", Value: d.AccountNumber, Reason: "Account number must be digits only"}
+	}
+	if len(number) < minAccountNumberLength || len(number) > maxAccountNumberLength {
+		return &EFTError{Field: "account number", Value: d.AccountNumber, Reason: fmt.Sprintf("Account number must be %d to %d digits", minAccountNumberLength, maxAccountNumberLength)}
+	}
+	if strings.Trim(number, "0") == "" {
+		return &EFTError{Field: "account number", Value: d.AccountNumber, Reason: "Account number cannot be all zeros"}
+	}
+
+	bsb, ok := normaliseBSB(d.BSB)
+	if !ok {
+		return &EFTError{Field: "BSB", Value: d.BSB, Reason: "BSB must be six digits, as NNN-NNN or NNNNNN"}
+	}
+
+	if strings.TrimSpace(d.BankName) == "" {
+		return &EFTError{Field: "bank name", Value: d.BankName, Reason: "Bank name is required"}
+	}
+
+	if dir == nil {
+		return nil
+	}
+
+	entry, known := dir.Lookup(bsb)
+	if entry.Branch == "" && dir.HasBranches() {
+		return &EFTError{Field: "BSB", Value: d.BSB, Reason: "BSB is not in the directory"}
+	}
+
+	if known && entry.Bank.Name != "" && !entry.Bank.Matches(d.BankName) {
+		return &EFTError{Field: "bank name", Value: d.BankName, Reason: fmt.Sprintf("BSB %s belongs to %s", bsb, entry.Bank.Name)}
+	}
+
+	return nil
+}
+
+// accountNumber returns the account number without spaces or hyphens.
+func (d EFTDestination) accountNumber() string {
+	return strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(d.AccountNumber))
+}
@@ -0,0 +1,488 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// executionPollInterval is the minimum time between requests made by an
+	// Execution while waiting on a child order, keeping it well within the
+	// client's rate limit.
+	executionPollInterval = 2 * time.Second
+
+	// executionVWAPInterval is how often a VWAP execution measures market
+	// volume and places a new child order.
+	executionVWAPInterval = 30 * time.Second
+)
+
+// ErrExecutionStopped is the error of an Execution which was stopped before
+// its volume was filled.
+var ErrExecutionStopped = errors.New("Execution stopped")
+
+// ExecutionProgress is a snapshot of the progress of an Execution.
+type ExecutionProgress struct {
+	Target    AmountWhole
+	Filled    AmountWhole
+	Remaining AmountWhole
+	Orders    []OrderID
+	Active    OrderID
+	Done      bool
+	Err       error
+}
+
+// Execution is a large order being worked in the market as a series of smaller
+// child orders by an execution algorithm (Iceberg, TWAP or VWAP). Child orders
+// are placed, polled and cancelled through the client, so they are subject to
+// the client's rate limiting. It is concurrency safe.
+type Execution struct {
+	c          *Client
+	currency   Currency
+	instrument Instrument
+	side       OrderSide
+	price      AmountWhole
+	target     AmountWhole
+
+	mu       sync.Mutex
+	filled   AmountWhole
+	orders   []OrderID
+	active   OrderID
+	err      error
+	finished bool
+
+	progress chan ExecutionProgress
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+// Iceberg works an order by showing only a slice of its volume at a time. Each
+// slice is a limit order at the provided price, placed once the previous slice
+// is fully matched.
+func (c *Client) Iceberg(currency Currency, instrument Instrument, side OrderSide, price, volume, slice AmountWhole) (*Execution, error) {
+	if price <= 0 {
+		return nil, errors.New("Iceberg orders require a limit price")
+	}
+
+	if slice <= 0 || slice > volume {
+		return nil, errors.New("Iceberg slice must be greater than zero and no more than the volume")
+	}
+
+	e, err := c.newExecution(currency, instrument, side, price, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	go e.run(func() error {
+		for e.remaining() > 0 {
+			size := slice
+			if r := e.remaining(); size > r {
+				size = r
+			}
+
+			if _, err := e.child(size, time.Time{}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return e, nil
+}
+
+// TWAP works an order by splitting it evenly over a duration. A child order is
+// placed at the start of each of the slices, and any volume left unmatched at
+// the end of a slice is cancelled and carried into the next. A price of zero
+// places market orders.
+func (c *Client) TWAP(currency Currency, instrument Instrument, side OrderSide, price, volume AmountWhole, duration time.Duration, slices int) (*Execution, error) {
+	if slices < 1 {
+		return nil, errors.New("TWAP requires at least one slice")
+	}
+
+	interval := duration / time.Duration(slices)
+	if interval < executionPollInterval {
+		return nil, fmt.Errorf("TWAP slices must be at least %s apart", executionPollInterval)
+	}
+
+	e, err := c.newExecution(currency, instrument, side, price, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	go e.run(func() error {
+		start := time.Now()
+		for i := 1; i <= slices && e.remaining() > 0; i++ {
+			// Each slice catches up to the volume scheduled by its end.
+			scheduled := AmountWhole(int64(volume) * int64(i) / int64(slices))
+			size := scheduled - e.filledVolume()
+			if size <= 0 {
+				continue
+			}
+
+			// Wait for the slice to start, in case the previous child filled
+			// before its slice ended.
+			if !e.sleep(start.Add(interval * time.Duration(i-1))) {
+				return ErrExecutionStopped
+			}
+
+			if _, err := e.child(size, start.Add(interval*time.Duration(i))); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return e, nil
+}
+
+// VWAP works an order by following the volume traded in the market over a
+// duration. At each interval a child order is placed for the provided
+// participation rate (such as 0.1 for 10%) of the volume traded by the market
+// since the previous interval. Volume left unmatched when the duration ends is
+// not placed. A price of zero places market orders.
+func (c *Client) VWAP(currency Currency, instrument Instrument, side OrderSide, price, volume AmountWhole, duration time.Duration, participation float64) (*Execution, error) {
+	if participation <= 0 || participation > 1 {
+		return nil, errors.New("VWAP participation must be greater than 0 and at most 1")
+	}
+
+	e, err := c.newExecution(currency, instrument, side, price, volume)
+	if err != nil {
+		return nil, err
+	}
+
+	go e.run(func() error {
+		trades, err := c.MarketTrades(instrument, currency, 0)
+		if err != nil {
+			return err
+		}
+
+		var cursor TradeID
+		for _, t := range *trades {
+			if t.TradeID > cursor {
+				cursor = t.TradeID
+			}
+		}
+
+		end := time.Now().Add(duration)
+		for e.remaining() > 0 && time.Now().Before(end) {
+			next := time.Now().Add(executionVWAPInterval)
+			if next.After(end) {
+				next = end
+			}
+
+			if !e.sleep(next) {
+				return ErrExecutionStopped
+			}
+
+			var traded AmountWhole
+			it := c.MarketTradesSince(instrument, currency, cursor)
+			for it.Next() {
+				traded += it.Trade().Amount.ToAmountWhole()
+			}
+			if err := it.Err(); err != nil {
+				return err
+			}
+			cursor = it.Checkpoint()
+
+			size := AmountWhole(float64(traded) * participation)
+			if r := e.remaining(); size > r {
+				size = r
+			}
+			if size <= 0 {
+				continue
+			}
+
+			deadline := time.Now().Add(executionVWAPInterval)
+			if deadline.After(end) {
+				deadline = end
+			}
+
+			if _, err := e.child(size, deadline); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	return e, nil
+}
+
+// newExecution validates and constructs an Execution.
+func (c *Client) newExecution(currency Currency, instrument Instrument, side OrderSide, price, volume AmountWhole) (*Execution, error) {
+	if side != Ask && side != Bid {
+		return nil, fmt.Errorf("Unknown order side %q", side)
+	}
+
+	if volume <= 0 {
+		return nil, errors.New("Execution volume must be greater than zero")
+	}
+
+	return &Execution{
+		c:          c,
+		currency:   currency,
+		instrument: instrument,
+		side:       side,
+		price:      price,
+		target:     volume,
+		progress:   make(chan ExecutionProgress, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}, nil
+}
+
+// Progress returns a channel which receives the latest progress whenever it
+// changes. Only the most recent progress is kept, and the channel is closed
+// once the execution is done.
+func (e *Execution) Progress() <-chan ExecutionProgress {
+	return e.progress
+}
+
+// Status returns the current progress of the execution.
+func (e *Execution) Status() ExecutionProgress {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.status()
+}
+
+// Stop stops the execution, cancelling any child order which is still open,
+// and waits for it to finish.
+func (e *Execution) Stop() ExecutionProgress {
+	e.stopOnce.Do(func() { close(e.stop) })
+
+	return e.Wait()
+}
+
+// Wait waits for the execution to finish and returns its final progress.
+func (e *Execution) Wait() ExecutionProgress {
+	<-e.done
+
+	return e.Status()
+}
+
+// run runs the algorithm and records its outcome.
+func (e *Execution) run(algorithm func() error) {
+	e.publish()
+
+	err := algorithm()
+	if err == nil && e.remaining() > 0 && e.stopped() {
+		err = ErrExecutionStopped
+	}
+
+	e.mu.Lock()
+	e.err = err
+	e.finished = true
+	e.mu.Unlock()
+
+	e.publish()
+	close(e.progress)
+	close(e.done)
+}
+
+// child places a child order for the volume and waits for it to reach a
+// terminal status. If the deadline is not zero and passes first, or the
+// execution is stopped, the order is cancelled. It returns the volume matched.
+func (e *Execution) child(volume AmountWhole, deadline time.Time) (AmountWhole, error) {
+	if e.stopped() {
+		return 0, ErrExecutionStopped
+	}
+
+	ordertype := Limit
+	if e.price == 0 {
+		ordertype = Market
+	}
+
+	ocr, err := e.c.OrderCreate(e.currency, e.instrument, e.price, volume, e.side, ordertype, "")
+	if err != nil {
+		return 0, err
+	}
+	if !ocr.Success {
+		return 0, fmt.Errorf("Failed to place child order (%d: %s)", ocr.ErrorCode, ocr.ErrorMessage)
+	}
+
+	e.mu.Lock()
+	e.orders = append(e.orders, ocr.ID)
+	e.active = ocr.ID
+	e.mu.Unlock()
+	e.publish()
+
+	var matched AmountWhole
+	for {
+		wait := time.Now().Add(executionPollInterval)
+		if !deadline.IsZero() && deadline.Before(wait) {
+			wait = deadline
+		}
+
+		cancel := !e.sleep(wait) || (!deadline.IsZero() && !time.Now().Before(deadline))
+
+		var o OrderDataItem
+		if cancel {
+			o, err = e.cancelChild(ocr.ID)
+		} else {
+			o, err = e.c.orderDetail(ocr.ID)
+		}
+		if o.OrderID == ocr.ID {
+			e.record(o, matched)
+			matched = tradedVolume(o)
+		}
+		if err != nil {
+			return matched, err
+		}
+
+		if o.Status.IsTerminal() || cancel {
+			e.mu.Lock()
+			e.active = 0
+			e.mu.Unlock()
+			e.publish()
+
+			if o.Status == OrderStatusFailed || o.Status == OrderStatusError {
+				return matched, fmt.Errorf("Child order %d failed (%s)", o.OrderID, o.ErrorMessage)
+			}
+			if !cancel && (o.Status == OrderStatusCancelled || o.Status == OrderStatusPartiallyCancelled) {
+				return matched, fmt.Errorf("Child order %d was cancelled outside of the execution", o.OrderID)
+			}
+			if e.stopped() {
+				return matched, ErrExecutionStopped
+			}
+
+			return matched, nil
+		}
+	}
+}
+
+// cancelChild cancels a child order and waits for it to reach a terminal
+// status, so that its volume is never placed again while it can still match.
+// A cancel which is rejected because the order has already completed is not an
+// error. The latest detail of the order is returned whenever it was fetched.
+func (e *Execution) cancelChild(id OrderID) (OrderDataItem, error) {
+	ocr, err := e.c.OrderCancel(id)
+	if err == nil && !ocr.Success {
+		err = fmt.Errorf("%d: %s", ocr.ErrorCode, ocr.ErrorMessage)
+	}
+	if err == nil {
+		for _, r := range ocr.Responses {
+			if r.ID == id && !r.Success {
+				err = fmt.Errorf("%d: %s", r.ErrorCode, r.ErrorMessage)
+			}
+		}
+	}
+
+	for attempt := 0; ; attempt++ {
+		o, derr := e.c.orderDetail(id)
+		if derr != nil {
+			return o, derr
+		}
+
+		if o.Status.IsTerminal() {
+			return o, nil
+		}
+
+		if err != nil {
+			return o, fmt.Errorf("Failed to cancel child order %d (%s)", id, err.Error())
+		}
+
+		if attempt == replaceCancelAttempts {
+			return o, fmt.Errorf("Child order %d is still %s after cancelling", id, o.Status)
+		}
+
+		time.Sleep(executionPollInterval)
+	}
+}
+
+// record adds any volume matched by the order since it was last seen.
+func (e *Execution) record(o OrderDataItem, previous AmountWhole) {
+	matched := tradedVolume(o)
+	if matched == previous {
+		return
+	}
+
+	e.mu.Lock()
+	e.filled += matched - previous
+	e.mu.Unlock()
+	e.publish()
+}
+
+// sleep waits until the provided time, returning false if the execution is
+// stopped first.
+func (e *Execution) sleep(until time.Time) bool {
+	t := time.NewTimer(time.Until(until))
+	defer t.Stop()
+
+	select {
+	case <-e.stop:
+		return false
+	case <-t.C:
+		return true
+	}
+}
+
+// stopped reports whether Stop has been called.
+func (e *Execution) stopped() bool {
+	select {
+	case <-e.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// filledVolume returns the volume matched so far.
+func (e *Execution) filledVolume() AmountWhole {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.filled
+}
+
+// remaining returns the volume left to match.
+func (e *Execution) remaining() AmountWhole {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.target - e.filled
+}
+
+// status returns the current progress. It must be called with the lock held.
+func (e *Execution) status() ExecutionProgress {
+	return ExecutionProgress{
+		Target:    e.target,
+		Filled:    e.filled,
+		Remaining: e.target - e.filled,
+		Orders:    append([]OrderID(nil), e.orders...),
+		Active:    e.active,
+		Done:      e.finished,
+		Err:       e.err,
+	}
+}
+
+// publish replaces any unread progress with the current progress.
+func (e *Execution) publish() {
+	e.mu.Lock()
+	p := e.status()
+	e.mu.Unlock()
+
+	select {
+	case <-e.progress:
+	default:
+	}
+
+	select {
+	case e.progress <- p:
+	default:
+	}
+}
+
+// tradedVolume returns the volume of an order which has been matched.
+func tradedVolume(o OrderDataItem) AmountWhole {
+	var v AmountWhole
+	for _, t := range o.Trades {
+		v += t.Volume
+	}
+
+	return v
+}
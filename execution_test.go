@@ -0,0 +1,125 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+// childStub is a server for a single child order, which answers cancels with
+// cancelled and details with the order at status.
+type childStub struct {
+	mu        sync.Mutex
+	creates   []OrderCreateRequest
+	cancels   int
+	cancelled bool
+	status    OrderStatus
+	matched   AmountWhole
+}
+
+func (s *childStub) handle(t *testing.T) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		switch r.URL.Path {
+		case "/order/create":
+			var req OrderCreateRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				t.Fatal(err)
+			}
+			s.creates = append(s.creates, req)
+
+			writeJSON(t, w, OrderCreateResponse{Success: true, ID: 1})
+		case "/order/cancel":
+			s.cancels++
+
+			writeJSON(t, w, OrderCancelResponse{Success: true, Responses: []OrderCancelData{
+				{Success: s.cancelled, ErrorCode: 3, ErrorMessage: "Order cannot be cancelled", ID: 1},
+			}})
+		case "/order/detail":
+			o := OrderDataItem{OrderID: 1, Status: s.status, Volume: 100000000}
+			if s.matched > 0 {
+				o.Trades = []OrderTradeDataItem{{TradeID: 1, Volume: s.matched}}
+			}
+
+			writeJSON(t, w, OrderDetailResponse{Success: true, Orders: []OrderDataItem{o}})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	}
+}
+
+func TestTWAPAUDMarketBid(t *testing.T) {
+	t.Parallel()
+
+	// The child fills just as its slice ends, so the cancel is rejected.
+	stub := &childStub{status: OrderStatusFullyMatched, matched: 100000000}
+	c := newStubClient(t, stub.handle(t))
+
+	e, err := c.TWAP(CurrencyAUD, InstrumentBitcoin, Bid, 0, 100000000, executionPollInterval, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := waitExecution(t, e)
+	if p.Err != nil {
+		t.Fatalf("Err = %v, want nil", p.Err)
+	}
+	if p.Filled != 100000000 || p.Remaining != 0 {
+		t.Errorf("filled %d remaining %d, want 100000000 and 0", p.Filled, p.Remaining)
+	}
+
+	if len(stub.creates) != 1 {
+		t.Fatalf("placed %d child orders, want 1", len(stub.creates))
+	}
+	if req := stub.creates[0]; req.Currency != CurrencyAUD || req.OrderSide != Bid || req.OrderType != Market {
+		t.Errorf("child order = %+v, want an AUD market bid", req)
+	}
+}
+
+func TestTWAPCancelRejected(t *testing.T) {
+	t.Parallel()
+
+	// The child is still open and the cancel is rejected, so its volume must
+	// not be placed again.
+	stub := &childStub{status: OrderStatusPartiallyMatched, matched: 40000000}
+	c := newStubClient(t, stub.handle(t))
+
+	e, err := c.TWAP(CurrencyAUD, InstrumentBitcoin, Bid, 0, 100000000, 2*executionPollInterval, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := waitExecution(t, e)
+	if p.Err == nil {
+		t.Fatal("Err = nil, want an error")
+	}
+	if p.Filled != 40000000 {
+		t.Errorf("filled %d, want 40000000", p.Filled)
+	}
+	if len(stub.creates) != 1 || stub.cancels != 1 {
+		t.Errorf("placed %d and cancelled %d child orders, want 1 and 1", len(stub.creates), stub.cancels)
+	}
+}
+
+// waitExecution waits for an execution to finish, failing the test if it takes
+// much longer than its duration.
+func waitExecution(t *testing.T, e *Execution) ExecutionProgress {
+	t.Helper()
+
+	done := make(chan ExecutionProgress, 1)
+	go func() { done <- e.Wait() }()
+
+	select {
+	case p := <-done:
+		return p
+	case <-time.After(10 * executionPollInterval):
+		e.Stop()
+		t.Fatal("execution did not finish")
+	}
+
+	return ExecutionProgress{}
+}
@@ -0,0 +1,351 @@
+package btcmarkets
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+)
+
+// exportTimeFormat is the format of every timestamp written by an Exporter,
+// which are always in UTC.
+const exportTimeFormat = "2006-01-02T15:04:05.000Z"
+
+// ExportFormat is the file format written by an Exporter.
+type ExportFormat string
+
+// Enumerated export formats.
+const (
+	// ExportCSV writes comma separated values with a header row.
+	ExportCSV ExportFormat = "csv"
+
+	// ExportJSONLines writes one JSON object per line.
+	ExportJSONLines ExportFormat = "jsonl"
+
+	// ExportColumnar writes a single JSON document holding an array of values
+	// per column, in the style of columnar formats such as Parquet. It is
+	// written when the Exporter is closed, and cannot be appended to.
+	ExportColumnar ExportFormat = "columnar"
+)
+
+// exportField is a single named value of an exported record. Numbers are
+// written unquoted where the format allows.
+type exportField struct {
+	name   string
+	value  string
+	number bool
+}
+
+func exportString(name, value string) exportField {
+	return exportField{name: name, value: value}
+}
+
+func exportInt(name string, value int64) exportField {
+	return exportField{name: name, value: strconv.FormatInt(value, 10), number: true}
+}
+
+func exportAmount(name string, value AmountWhole) exportField {
+	return exportField{name: name, value: value.DecimalString(), number: true}
+}
+
+func exportTime(name string, value time.Time) exportField {
+	return exportField{name: name, value: value.UTC().Format(exportTimeFormat)}
+}
+
+// Exporter writes normalised records of a single kind (such as orders) to a
+// file. Amounts are written as exact decimals and timestamps in UTC.
+type Exporter struct {
+	w      io.Writer
+	closer io.Closer
+	format ExportFormat
+	header bool
+
+	csv     *csv.Writer
+	columns []string
+	data    map[string][]json.RawMessage
+	rows    int
+}
+
+// NewExporter constructs a new Exporter writing to w. When header is true a
+// header row is written before the first CSV record.
+func NewExporter(w io.Writer, format ExportFormat, header bool) (*Exporter, error) {
+	e := &Exporter{
+		w:      w,
+		format: format,
+		header: header,
+	}
+
+	switch format {
+	case ExportCSV:
+		e.csv = csv.NewWriter(w)
+	case ExportJSONLines:
+	case ExportColumnar:
+		e.data = make(map[string][]json.RawMessage)
+	default:
+		return nil, fmt.Errorf("Unknown export format %q", format)
+	}
+
+	return e, nil
+}
+
+// OpenExportFile opens a file for export, appending to it if it already
+// exists. A CSV header is only written when the file is empty, so that
+// incremental exports (continuing from the checkpoint of the last export)
+// produce a single table. Columnar files cannot be appended to.
+func OpenExportFile(path string, format ExportFormat) (*Exporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open export file (%s)", err.Error())
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("Failed to open export file (%s)", err.Error())
+	}
+
+	if format == ExportColumnar && info.Size() > 0 {
+		f.Close()
+		return nil, errors.New("Columnar export files cannot be appended to")
+	}
+
+	e, err := NewExporter(f, format, info.Size() == 0)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	e.closer = f
+
+	return e, nil
+}
+
+// Rows returns the number of records written. Columnar records are only written
+// to the file by Close.
+func (e *Exporter) Rows() int {
+	return e.rows
+}
+
+// write writes a single record.
+func (e *Exporter) write(record []exportField) error {
+	switch e.format {
+	case ExportCSV:
+		if e.header {
+			names := make([]string, len(record))
+			for i, f := range record {
+				names[i] = f.name
+			}
+			if err := e.csv.Write(names); err != nil {
+				return fmt.Errorf("Failed to write export (%s)", err.Error())
+			}
+			e.header = false
+		}
+
+		values := make([]string, len(record))
+		for i, f := range record {
+			values[i] = f.value
+		}
+		if err := e.csv.Write(values); err != nil {
+			return fmt.Errorf("Failed to write export (%s)", err.Error())
+		}
+
+		// Flush every record so that a write error is reported for the
+		// record which caused it, keeping the returned checkpoints exact.
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return fmt.Errorf("Failed to write export (%s)", err.Error())
+		}
+
+	case ExportJSONLines:
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for i, f := range record {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			name, _ := json.Marshal(f.name)
+			buf.Write(name)
+			buf.WriteByte(':')
+			buf.Write(jsonValue(f))
+		}
+		buf.WriteString("}\n")
+
+		if _, err := e.w.Write(buf.Bytes()); err != nil {
+			return fmt.Errorf("Failed to write export (%s)", err.Error())
+		}
+
+	case ExportColumnar:
+		if e.columns == nil {
+			for _, f := range record {
+				e.columns = append(e.columns, f.name)
+			}
+		}
+		for _, f := range record {
+			e.data[f.name] = append(e.data[f.name], jsonValue(f))
+		}
+	}
+
+	e.rows++
+
+	return nil
+}
+
+// Close flushes any buffered records, writing the document of a columnar
+// export, and closes the file if the Exporter opened it.
+func (e *Exporter) Close() error {
+	var err error
+
+	switch e.format {
+	case ExportCSV:
+		e.csv.Flush()
+		err = e.csv.Error()
+
+	case ExportColumnar:
+		data := make(map[string][]json.RawMessage, len(e.columns))
+		for _, c := range e.columns {
+			data[c] = e.data[c]
+		}
+
+		err = json.NewEncoder(e.w).Encode(struct {
+			Rows    int                          `json:"rows"`
+			Columns []string                     `json:"columns"`
+			Data    map[string][]json.RawMessage `json:"data"`
+		}{e.rows, e.columns, data})
+	}
+
+	if e.closer != nil {
+		if cerr := e.closer.Close(); err == nil {
+			err = cerr
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("Failed to write export (%s)", err.Error())
+	}
+
+	return nil
+}
+
+// jsonValue returns the JSON encoding of a field's value.
+func jsonValue(f exportField) json.RawMessage {
+	if f.number {
+		return json.RawMessage(f.value)
+	}
+
+	v, _ := json.Marshal(f.value)
+
+	return v
+}
+
+// ExportOrders writes every order in the market's order history created after
+// the "since" order ID, returning the ID of the last order written. Passing
+// that ID to the next export appends only newer orders.
+//
+// Orders are written as they are at the time of the export, so an order which
+// was still open is not updated by later exports appending from the returned
+// ID. Use OrderDetail to find the final state of such orders, or export again
+// from before the oldest of them into a new file.
+func (c *Client) ExportOrders(e *Exporter, currency Currency, instrument Instrument, since OrderID) (OrderID, error) {
+	last := since
+
+	it := c.OrderHistoryAll(currency, instrument, since)
+	for it.Next() {
+		o := it.Order()
+
+		err := e.write([]exportField{
+			exportInt("id", int64(o.OrderID)),
+			exportTime("created", o.CreatedTime()),
+			exportString("instrument", string(o.Instrument)),
+			exportString("currency", string(o.Currency)),
+			exportString("side", string(o.OrderSide)),
+			exportString("type", string(o.OrderType)),
+			exportString("status", string(o.Status)),
+			exportAmount("price", o.Price),
+			exportAmount("volume", o.Volume),
+			exportAmount("open_volume", o.VolumeOpen),
+			exportAmount("filled_volume", tradedVolume(o)),
+			exportInt("trades", int64(len(o.Trades))),
+			exportString("error", o.ErrorMessage),
+		})
+		if err != nil {
+			return last, err
+		}
+		last = o.OrderID
+	}
+
+	return last, it.Err()
+}
+
+// ExportTrades writes every trade in the market's trade history after the
+// "since" trade ID, returning the ID of the last trade written.
+func (c *Client) ExportTrades(e *Exporter, currency Currency, instrument Instrument, since TradeID) (TradeID, error) {
+	last := since
+
+	it := c.OrderTradeHistoryAll(currency, instrument, since)
+	for it.Next() {
+		t := it.Trade()
+
+		err := e.write([]exportField{
+			exportInt("id", int64(t.TradeID)),
+			exportInt("order_id", int64(t.OrderID)),
+			exportTime("created", t.CreatedTime()),
+			exportString("instrument", string(instrument)),
+			exportString("currency", string(currency)),
+			exportString("side", string(t.OrderSide)),
+			exportAmount("price", t.Price),
+			exportAmount("volume", t.Volume),
+			exportAmount("notional", t.Price.Mul(t.Volume)),
+			exportAmount("fee", t.Fee),
+			exportString("description", t.Description),
+		})
+		if err != nil {
+			return last, err
+		}
+		last = t.TradeID
+	}
+
+	return last, it.Err()
+}
+
+// ExportTransfers writes every deposit and withdrawal in the account's fund
+// transfer history after the "since" transfer ID, returning the ID of the last
+// transfer written. As with ExportOrders, a transfer which had not reached a
+// terminal status is not updated by later exports appending from that ID.
+func (c *Client) ExportTransfers(e *Exporter, since TransferID) (TransferID, error) {
+	last := since
+
+	it := c.FundTransferHistoryAll(since)
+	for it.Next() {
+		ft := it.Transfer()
+
+		var address, txid string
+		if ft.CryptoDetail != nil {
+			address, txid = ft.CryptoDetail.Address, ft.CryptoDetail.TxID
+		}
+
+		err := e.write([]exportField{
+			exportInt("id", int64(ft.FundTransferID)),
+			exportTime("created", ft.CreatedTime()),
+			exportTime("updated", ft.LastUpdateTime()),
+			exportString("type", string(ft.TransferType)),
+			exportString("status", string(ft.Status)),
+			exportString("currency", string(ft.Currency)),
+			exportAmount("amount", ft.Amount),
+			exportAmount("fee", ft.Fee),
+			exportString("address", address),
+			exportString("txid", txid),
+			exportString("description", ft.Description),
+			exportString("error", ft.ErrorMessage),
+		})
+		if err != nil {
+			return last, err
+		}
+		last = ft.FundTransferID
+	}
+
+	return last, it.Err()
+}
@@ -0,0 +1,49 @@
+package btcmarkets
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// failingWriter accepts a number of writes and then fails every write.
+type failingWriter struct {
+	writes int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.writes == 0 {
+		return 0, errors.New("disk full")
+	}
+
+	w.writes--
+	return len(p), nil
+}
+
+func TestExportTransfersWriteFailure(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var transfers []FundTransferDataItem
+		if r.URL.Query().Get("since") == "" {
+			for id := 3; id >= 1; id-- {
+				transfers = append(transfers, FundTransferDataItem{FundTransferID: TransferID(id), Status: TransferStatusComplete})
+			}
+		}
+
+		writeJSON(t, w, FundTransferHistoryResponse{Success: true, FundTransfers: transfers})
+	})
+
+	// The header and first record are flushed together, then the second record,
+	// before the disk fills.
+	e, err := NewExporter(&failingWriter{writes: 2}, ExportCSV, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	last, err := c.ExportTransfers(e, 0)
+	if err == nil {
+		t.Fatal("ExportTransfers error = nil, want the write error")
+	}
+	if last != 2 {
+		t.Errorf("last = %d, want 2", last)
+	}
+}
@@ -1,7 +1,14 @@
 package btcmarkets
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 /*
@@ -10,37 +17,125 @@ FUNDTRANSFER [HTTP ENDPOINTS]
 Endpoints:
 POST /fundtransfer/withdrawCrypto	(Rate Limited: 10x / 10sec)
 POST /fundtransfer/withdrawEFT		(Rate Limited: 10x / 10sec)
-GET /fundtransfer/history 			**preview only, not available in API yet**
+GET /fundtransfer/history 			(Rate Limited: 10x / 10sec) **preview**
+GET /fundtransfer/depositAddress	(Rate Limited: 10x / 10sec)
 */
 
 // FundTransferWithdrawCryptoRequest represents the required information for a crypto tranfer.
+// Tag is only sent for currencies which support destination tags (see
+// CryptoDestination).
 type FundTransferWithdrawCryptoRequest struct {
 	Amount   AmountWhole `json:"amount"`
 	Address  string      `json:"address"`
 	Currency Currency    `json:"currency"`
+	Tag      string      `json:"-"`
+}
+
+// MarshalJSON encodes the request, appending any destination tag to the
+// address in the "?dt=" form expected by the API.
+func (r FundTransferWithdrawCryptoRequest) MarshalJSON() ([]byte, error) {
+	address := r.Address
+	if r.Tag != "" {
+		address += destinationTagSeparator + r.Tag
+	}
+
+	return json.Marshal(struct {
+		Amount   AmountWhole `json:"amount"`
+		Address  string      `json:"address"`
+		Currency Currency    `json:"currency"`
+	}{r.Amount, address, r.Currency})
+}
+
+// destinationTagSeparator separates an address from its destination tag when
+// they are written as one string, as in "rXXXX?dt=1234".
+const destinationTagSeparator = "?dt="
+
+// destinationTags are the currencies which support destination tags.
+var destinationTags = map[Currency]bool{
+	CurrencyRipple: true,
+}
+
+// CryptoDestination is the destination of a crypto withdrawal. Tag is the
+// destination tag which identifies the recipient at a shared address (such as
+// another exchange's XRP wallet), and is only supported by some currencies.
+type CryptoDestination struct {
+	Address string
+	Tag     string
+}
+
+// ParseCryptoDestination splits an address written with its destination tag,
+// as in "rXXXX?dt=1234", into a CryptoDestination. Addresses without a tag are
+// returned unchanged.
+func ParseCryptoDestination(address string) CryptoDestination {
+	if i := strings.Index(address, destinationTagSeparator); i >= 0 {
+		return CryptoDestination{Address: address[:i], Tag: address[i+len(destinationTagSeparator):]}
+	}
+
+	return CryptoDestination{Address: address}
+}
+
+// Validate checks that the destination is valid for the currency, returning an
+// *AddressError if it is not.
+func (d CryptoDestination) Validate(currency Currency) error {
+	if err := ValidateAddress(currency, d.Address); err != nil {
+		return err
+	}
+
+	if d.Tag != "" {
+		if !destinationTags[currency] {
+			return &AddressError{Currency: currency, Address: d.Address, Reason: "Currency does not support destination tags"}
+		}
+		if err := ValidateDestinationTag(d.Tag); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// String returns the destination as the address, with any tag in "?dt=" form.
+func (d CryptoDestination) String() string {
+	if d.Tag != "" {
+		return d.Address + destinationTagSeparator + d.Tag
+	}
+
+	return d.Address
 }
 
 // FundTransferWithdrawCryptoResponse represents the JSON data structure
 // returned from the POST /fundtransfer/withdrawCrypto endpoint.
 type FundTransferWithdrawCryptoResponse struct {
-	Success        bool        `json:"success"`
-	ErrorCode      string      `json:"errorCode"`
-	ErrorMessage   string      `json:"errorMessage"`
-	Status         string      `json:"status"`
-	FundTransferID int64       `json:"fundTransferId"`
-	Description    string      `json:"description"`
-	Created        int64       `json:"creationTime"`
-	Currency       Currency    `json:"currency"`
-	Amount         AmountWhole `json:"amount"`
-	Fee            AmountWhole `json:"fee"`
-}
-
-// WithdrawCrypto implements the POST /fundtransfer/withdrawCrypto API endpoint
+	Success        bool           `json:"success"`
+	ErrorCode      string         `json:"errorCode"`
+	ErrorMessage   string         `json:"errorMessage"`
+	Status         TransferStatus `json:"status"`
+	FundTransferID TransferID     `json:"fundTransferId"`
+	Description    string         `json:"description"`
+	Created        int64          `json:"creationTime"`
+	Currency       Currency       `json:"currency"`
+	Amount         AmountWhole    `json:"amount"`
+	Fee            AmountWhole    `json:"fee"`
+}
+
+// WithdrawCrypto implements the POST /fundtransfer/withdrawCrypto API endpoint.
+// An XRP destination tag may be included in the address as "rXXXX?dt=1234".
 func (c *Client) WithdrawCrypto(amount AmountWhole, currency Currency, address string) (*FundTransferWithdrawCryptoResponse, error) {
+	return c.WithdrawCryptoTo(amount, currency, ParseCryptoDestination(address))
+}
+
+// WithdrawCryptoTo implements the POST /fundtransfer/withdrawCrypto API endpoint
+// for a destination with an optional tag, such as an XRP address at another
+// exchange.
+func (c *Client) WithdrawCryptoTo(amount AmountWhole, currency Currency, dest CryptoDestination) (*FundTransferWithdrawCryptoResponse, error) {
+	if err := dest.Validate(currency); err != nil {
+		return nil, err
+	}
+
 	ftwcReq := &FundTransferWithdrawCryptoRequest{
 		Amount:   amount,
-		Address:  address,
+		Address:  dest.Address,
 		Currency: currency,
+		Tag:      dest.Tag,
 	}
 
 	ftwcRes := &FundTransferWithdrawCryptoResponse{}
@@ -70,16 +165,24 @@ type FundTransferWithdrawEFTResponse struct {
 	FundTransferWithdrawCryptoResponse
 }
 
-// WithdrawEFT implements the POST /fundtransfer/withdrawEFT API endpoint
-func (c *Client) WithdrawEFT(amount AmountWhole, currency Currency, accountName, accountNumber, bankName, bsb string) (*FundTransferWithdrawEFTResponse, error) {
+// WithdrawEFT implements the POST /fundtransfer/withdrawEFT API endpoint. The
+// destination is validated (see EFTDestination.Validate) before the request is
+// sent, and its BSB sent in NNN-NNN form.
+func (c *Client) WithdrawEFT(amount AmountWhole, currency Currency, dest EFTDestination) (*FundTransferWithdrawEFTResponse, error) {
 	if currency != CurrencyAUD {
 		return nil, errors.New("Only AUD currency is currently supported by the API/service")
 	}
 
+	if err := dest.Validate(); err != nil {
+		return nil, err
+	}
+
+	bsb, _ := normaliseBSB(dest.BSB)
+
 	ftweReq := &FundTransferWithdrawEFTRequest{
-		AccountName:   accountName,
-		AccountNumber: accountNumber,
-		BankName:      bankName,
+		AccountName:   strings.TrimSpace(dest.AccountName),
+		AccountNumber: dest.accountNumber(),
+		BankName:      strings.TrimSpace(dest.BankName),
 		BSB:           bsb,
 		Amount:        amount,
 		Currency:      currency,
@@ -94,3 +197,209 @@ func (c *Client) WithdrawEFT(amount AmountWhole, currency Currency, accountName,
 
 	return ftweRes, nil
 }
+
+// TransferID is a unique fund transfer id.
+type TransferID int64
+
+// TransferStatus is a string which describes the status of a fund transfer.
+type TransferStatus string
+
+// Enumerated transfer statuses.
+const (
+	TransferStatusPendingAuthorisation TransferStatus = "Pending Authorization"
+	TransferStatusAccepted             TransferStatus = "Accepted"
+	TransferStatusProcessing           TransferStatus = "Processing"
+	TransferStatusComplete             TransferStatus = "Complete"
+	TransferStatusFailed               TransferStatus = "Failed"
+	TransferStatusCancelled            TransferStatus = "Cancelled"
+)
+
+// IsTerminal reports whether a transfer with the status will never change again.
+func (s TransferStatus) IsTerminal() bool {
+	return s == TransferStatusComplete || s == TransferStatusFailed || s == TransferStatusCancelled
+}
+
+// TransferType is the direction of a fund transfer.
+type TransferType string
+
+// Enumerated transfer types.
+const (
+	TransferDeposit  TransferType = "DEPOSIT"
+	TransferWithdraw TransferType = "WITHDRAW"
+)
+
+// FundTransferCryptoDetail is the blockchain detail of a crypto transfer.
+type FundTransferCryptoDetail struct {
+	Address string `json:"address"`
+	TxID    string `json:"txId"`
+}
+
+// FundTransferDataItem is the data structure that represents a single deposit
+// or withdrawal.
+type FundTransferDataItem struct {
+	FundTransferID TransferID                `json:"fundTransferId"`
+	TransferType   TransferType              `json:"transferType"`
+	Status         TransferStatus            `json:"status"`
+	Description    string                    `json:"description"`
+	ErrorMessage   string                    `json:"errorMessage"`
+	Created        int64                     `json:"creationTime"`
+	LastUpdate     int64                     `json:"lastUpdate"`
+	Currency       Currency                  `json:"currency"`
+	Amount         AmountWhole               `json:"amount"`
+	Fee            AmountWhole               `json:"fee"`
+	CryptoDetail   *FundTransferCryptoDetail `json:"cryptoPaymentDetail"`
+}
+
+// CreatedTime returns the creation time of the transfer.
+func (ftdi *FundTransferDataItem) CreatedTime() time.Time {
+	return millisTime(ftdi.Created)
+}
+
+// LastUpdateTime returns the time the transfer was last updated.
+func (ftdi *FundTransferDataItem) LastUpdateTime() time.Time {
+	return millisTime(ftdi.LastUpdate)
+}
+
+// FundTransferHistoryResponse represents the JSON data structure returned from
+// the GET /fundtransfer/history endpoint.
+type FundTransferHistoryResponse struct {
+	Success       bool                   `json:"success"`
+	ErrorCode     string                 `json:"errorCode"`
+	ErrorMessage  string                 `json:"errorMessage"`
+	FundTransfers []FundTransferDataItem `json:"fundTransfers"`
+}
+
+// FundTransferHistory implements the GET /fundtransfer/history API endpoint.
+//
+// "limit" and "since" are optional parameters which, when greater than 0, limit
+// the number of transfers returned and only return transfers since the
+// supplied transfer ID.
+func (c *Client) FundTransferHistory(limit int, since TransferID) (*FundTransferHistoryResponse, error) {
+	query := url.Values{}
+	if limit > 0 {
+		query.Set("limit", strconv.Itoa(limit))
+	}
+	if since > 0 {
+		query.Set("since", strconv.FormatInt(int64(since), 10))
+	}
+
+	path := "/fundtransfer/history"
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+
+	fthRes := &FundTransferHistoryResponse{}
+
+	err := c.Get(path, fthRes, rateLimit10)
+	if err != nil {
+		return nil, err
+	}
+
+	return fthRes, nil
+}
+
+// FundTransferIterator walks the account's fund transfer history oldest first,
+// one page at a time. It behaves in the same way as OrderIterator.
+type FundTransferIterator struct {
+	c       *Client
+	cursor  TransferID
+	page    []FundTransferDataItem
+	current FundTransferDataItem
+	err     error
+	done    bool
+}
+
+// FundTransferHistoryAll returns an iterator over every fund transfer made
+// after the supplied transfer ID.
+func (c *Client) FundTransferHistoryAll(since TransferID) *FundTransferIterator {
+	return &FundTransferIterator{
+		c:      c,
+		cursor: since,
+	}
+}
+
+// Next advances the iterator to the next transfer, fetching a new page when
+// required. It returns false when the transfers are exhausted or an error occurs.
+func (it *FundTransferIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 && !it.done {
+		fthRes, err := it.c.FundTransferHistory(orderPageLimit, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if !fthRes.Success {
+			it.err = fmt.Errorf("Failed to fetch fund transfer history (%s: %s)", fthRes.ErrorCode, fthRes.ErrorMessage)
+			return false
+		}
+
+		it.page = make([]FundTransferDataItem, 0, len(fthRes.FundTransfers))
+		for _, ft := range fthRes.FundTransfers {
+			if ft.FundTransferID > it.cursor {
+				it.page = append(it.page, ft)
+			}
+		}
+		sort.Slice(it.page, func(i, j int) bool { return it.page[i].FundTransferID < it.page[j].FundTransferID })
+
+		it.done = len(fthRes.FundTransfers) < orderPageLimit || len(it.page) == 0
+	}
+
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	it.cursor = it.current.FundTransferID
+
+	return true
+}
+
+// Transfer returns the transfer the iterator is currently positioned on.
+func (it *FundTransferIterator) Transfer() FundTransferDataItem {
+	return it.current
+}
+
+// Checkpoint returns the ID of the last transfer returned by the iterator.
+func (it *FundTransferIterator) Checkpoint() TransferID {
+	return it.cursor
+}
+
+// Err returns the error, if any, which stopped the iteration.
+func (it *FundTransferIterator) Err() error {
+	return it.err
+}
+
+// FundTransferDepositAddressResponse represents the JSON data structure
+// returned from the GET /fundtransfer/depositAddress endpoint. Tag is the
+// destination tag which must accompany deposits of currencies which use them
+// (such as XRP).
+type FundTransferDepositAddressResponse struct {
+	Success      bool     `json:"success"`
+	ErrorCode    string   `json:"errorCode"`
+	ErrorMessage string   `json:"errorMessage"`
+	Currency     Currency `json:"currency"`
+	Address      string   `json:"address"`
+	Tag          string   `json:"tag"`
+}
+
+// DepositAddress implements the GET /fundtransfer/depositAddress API endpoint,
+// returning the address to deposit the currency to.
+func (c *Client) DepositAddress(currency Currency) (*FundTransferDepositAddressResponse, error) {
+	if currency == CurrencyAUD {
+		return nil, errors.New("AUD is deposited by EFT, not to an address")
+	}
+
+	ftdaRes := &FundTransferDepositAddressResponse{}
+
+	err := c.Get("/fundtransfer/depositAddress?currency="+url.QueryEscape(string(currency)), ftdaRes, rateLimit10)
+	if err != nil {
+		return nil, err
+	}
+
+	return ftdaRes, nil
+}
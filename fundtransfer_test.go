@@ -0,0 +1,199 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// newStubClient returns a Client which sends its requests to a local server
+// running the handler, with rate limiting fast enough for tests.
+func newStubClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+
+	rate10 := new(rateLimit)
+	rate10.Start(time.Millisecond, rateLimit10)
+
+	return &Client{
+		apikey:  "key",
+		secret:  []byte("secret"),
+		rate10:  rate10,
+		baseURL: srv.URL,
+	}
+}
+
+// writeJSON writes v as the JSON response body.
+func writeJSON(t *testing.T, w http.ResponseWriter, v interface{}) {
+	t.Helper()
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFundTransferHistory(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fundtransfer/history" {
+			t.Errorf("path = %q, want /fundtransfer/history", r.URL.Path)
+		}
+		if got := r.URL.Query().Get("limit"); got != "10" {
+			t.Errorf("limit = %q, want 10", got)
+		}
+		if got := r.URL.Query().Get("since"); got != "42" {
+			t.Errorf("since = %q, want 42", got)
+		}
+		if r.Header.Get("apikey") != "key" || r.Header.Get("signature") == "" {
+			t.Error("request is not signed")
+		}
+
+		w.Write([]byte(`{"success":true,"errorCode":null,"errorMessage":null,"fundTransfers":[{
+			"fundTransferId":43,"transferType":"WITHDRAW","status":"Complete",
+			"description":"BTC withdraw","errorMessage":null,
+			"creationTime":1500000000000,"lastUpdate":1500000060000,
+			"currency":"BTC","amount":150000000,"fee":20000,
+			"cryptoPaymentDetail":{"address":"1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2","txId":"abc"}}]}`))
+	})
+
+	res, err := c.FundTransferHistory(10, 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !res.Success || len(res.FundTransfers) != 1 {
+		t.Fatalf("response = %+v, want one transfer", res)
+	}
+
+	ft := res.FundTransfers[0]
+	if ft.FundTransferID != 43 || ft.TransferType != TransferWithdraw || ft.Status != TransferStatusComplete {
+		t.Errorf("transfer = %+v", ft)
+	}
+	if ft.Currency != CurrencyBitcoin || ft.Amount != 150000000 || ft.Fee != 20000 {
+		t.Errorf("amounts = %s %d fee %d", ft.Currency, ft.Amount, ft.Fee)
+	}
+	if ft.CryptoDetail == nil || ft.CryptoDetail.TxID != "abc" {
+		t.Errorf("crypto detail = %+v", ft.CryptoDetail)
+	}
+	if want := time.Unix(1500000060, 0); !ft.LastUpdateTime().Equal(want) {
+		t.Errorf("LastUpdateTime() = %s, want %s", ft.LastUpdateTime(), want)
+	}
+}
+
+func TestFundTransferHistoryAll(t *testing.T) {
+	const total = 450
+
+	var requests int
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		since, _ := strconv.Atoi(r.URL.Query().Get("since"))
+		if limit != orderPageLimit {
+			t.Errorf("limit = %d, want %d", limit, orderPageLimit)
+		}
+
+		// Pages overlap by including the "since" transfer itself, and are
+		// returned newest first.
+		start := since
+		if start == 0 {
+			start = 1
+		}
+		end := start + limit - 1
+		if end > total {
+			end = total
+		}
+
+		var transfers []FundTransferDataItem
+		for id := end; id >= start; id-- {
+			transfers = append(transfers, FundTransferDataItem{FundTransferID: TransferID(id), Status: TransferStatusComplete})
+		}
+
+		writeJSON(t, w, FundTransferHistoryResponse{Success: true, FundTransfers: transfers})
+	})
+
+	it := c.FundTransferHistoryAll(0)
+
+	var last TransferID
+	var n int
+	for it.Next() {
+		id := it.Transfer().FundTransferID
+		if id != last+1 {
+			t.Fatalf("transfer %d followed %d", id, last)
+		}
+		last = id
+		n++
+	}
+
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != total {
+		t.Errorf("iterated %d transfers, want %d", n, total)
+	}
+	if it.Checkpoint() != total {
+		t.Errorf("Checkpoint() = %d, want %d", it.Checkpoint(), total)
+	}
+	if requests != 3 {
+		t.Errorf("made %d requests, want 3", requests)
+	}
+}
+
+func TestFundTransferHistoryAllFailure(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(t, w, FundTransferHistoryResponse{ErrorCode: "3", ErrorMessage: "Invalid argument."})
+	})
+
+	it := c.FundTransferHistoryAll(5)
+	if it.Next() {
+		t.Fatal("Next() = true, want false")
+	}
+
+	if it.Err() == nil {
+		t.Fatal("Err() = nil, want an error")
+	}
+	if it.Checkpoint() != 5 {
+		t.Errorf("Checkpoint() = %d, want 5", it.Checkpoint())
+	}
+}
+
+func TestDepositAddress(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/fundtransfer/depositAddress" {
+			t.Errorf("path = %q, want /fundtransfer/depositAddress", r.URL.Path)
+		}
+		if r.URL.RawQuery != "currency=X+R%26P" {
+			t.Errorf("query = %q, want currency=X+R%%26P", r.URL.RawQuery)
+		}
+
+		writeJSON(t, w, FundTransferDepositAddressResponse{
+			Success:  true,
+			Currency: Currency(r.URL.Query().Get("currency")),
+			Address:  "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh",
+			Tag:      "12345",
+		})
+	})
+
+	res, err := c.DepositAddress("X R&P")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res.Currency != "X R&P" || res.Address != "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh" || res.Tag != "12345" {
+		t.Errorf("response = %+v", res)
+	}
+}
+
+func TestDepositAddressAUD(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request should be sent for AUD")
+	})
+
+	if _, err := c.DepositAddress(CurrencyAUD); err == nil {
+		t.Fatal("DepositAddress(AUD) error = nil, want an error")
+	}
+}
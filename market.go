@@ -3,6 +3,7 @@ package btcmarkets
 import (
 	"bytes"
 	"fmt"
+	"sort"
 )
 
 /*
@@ -41,8 +42,8 @@ func (c *Client) MarketTick(instrument Instrument, currency Currency) (*MarketTi
 // MarketOrderbookResponse represents the JSON data structure returned from
 // the GET /market/:instrument/:currency/orderbook endpoint.
 type MarketOrderbookResponse struct {
-	Bids       [][]float64 `json:"bids"`
-	Asks       [][]float64 `json:"asks"`
+	Bids       PriceLevels `json:"bids"`
+	Asks       PriceLevels `json:"asks"`
 	Currency   Currency    `json:"currency"`
 	Instrument Instrument  `json:"instrument"`
 	Timestamp  int64       `json:"timestamp"`
@@ -67,10 +68,17 @@ type MarketTradesResponse []MarketTradeDataItem
 // MarketTrades implements the GET /market/:instrument/:currency/trades endpoint.
 //
 // "since" is an optional parameter which, when greater than 0 will only get MarketTrades
-// which occurred since the supplied trade ID.
-func (c *Client) MarketTrades(instrument Instrument, currency Currency, since OrderID) (*MarketTradesResponse, error) {
+// which occurred since the supplied trade ID. Only a single page of trades is
+// returned, use MarketTradesSince to walk the complete history.
+func (c *Client) MarketTrades(instrument Instrument, currency Currency, since TradeID) (*MarketTradesResponse, error) {
+	return c.marketTrades(instrument, currency, since, since > 0)
+}
+
+// marketTrades requests a page of trades, sending "since" (even when it is 0)
+// if withSince is true.
+func (c *Client) marketTrades(instrument Instrument, currency Currency, since TradeID, withSince bool) (*MarketTradesResponse, error) {
 	var sinceURI string
-	if since > 0 {
+	if withSince {
 		sinceURI = fmt.Sprintf("?since=%d", since)
 	}
 
@@ -110,3 +118,106 @@ type MarketTradeDataItem struct {
 func (mtdi *MarketTradeDataItem) String() string {
 	return fmt.Sprintf("Trade %d: %f - %f at %f", mtdi.TradeID, mtdi.Amount*mtdi.Price, mtdi.Amount, mtdi.Price)
 }
+
+// MarketTradeIterator walks the trade history of a market forward in time, one
+// page at a time. Pages are requested through the client as they are needed, so
+// they are subject to the client's rate limiting.
+//
+// The iterator is not concurrency safe.
+type MarketTradeIterator struct {
+	c          *Client
+	instrument Instrument
+	currency   Currency
+	cursor     TradeID
+	page       []MarketTradeDataItem
+	current    MarketTradeDataItem
+	err        error
+	done       bool
+}
+
+// MarketTradesSince returns an iterator over every trade in the market which
+// occurred after the supplied trade ID, oldest first. A "since" of 0 starts
+// from the beginning of the market's history, as "since" is always sent. To
+// resume after a crash, persist the iterator's Checkpoint and pass it as
+// "since" to a new iterator.
+//
+//	it := cl.MarketTradesSince(btcmarkets.InstrumentBitcoin, btcmarkets.CurrencyAUD, checkpoint)
+//	for it.Next() {
+//		trade := it.Trade()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (c *Client) MarketTradesSince(instrument Instrument, currency Currency, since TradeID) *MarketTradeIterator {
+	return &MarketTradeIterator{
+		c:          c,
+		instrument: instrument,
+		currency:   currency,
+		cursor:     since,
+	}
+}
+
+// Next advances the iterator to the next trade, fetching a new page when
+// required. It returns false when the history is exhausted or an error occurs.
+func (it *MarketTradeIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 && !it.done {
+		it.fetch()
+	}
+
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	it.cursor = it.current.TradeID
+
+	return true
+}
+
+// fetch requests the page of trades following the cursor. Trades at or before
+// the cursor are discarded, which removes any overlap between pages.
+func (it *MarketTradeIterator) fetch() {
+	mtr, err := it.c.marketTrades(it.instrument, it.currency, it.cursor, true)
+	if err != nil {
+		it.err = err
+		return
+	}
+
+	page := make([]MarketTradeDataItem, 0, len(*mtr))
+	seen := make(map[TradeID]bool, len(*mtr))
+	for _, t := range *mtr {
+		if t.TradeID <= it.cursor || seen[t.TradeID] {
+			continue
+		}
+		seen[t.TradeID] = true
+		page = append(page, t)
+	}
+
+	// The API returns the most recent trades first.
+	sort.Slice(page, func(i, j int) bool { return page[i].TradeID < page[j].TradeID })
+
+	it.page = page
+	it.done = len(page) == 0
+}
+
+// Trade returns the trade the iterator is currently positioned on.
+func (it *MarketTradeIterator) Trade() MarketTradeDataItem {
+	return it.current
+}
+
+// Checkpoint returns the ID of the last trade returned by the iterator, which
+// can be used to resume iteration from the following trade.
+func (it *MarketTradeIterator) Checkpoint() TradeID {
+	return it.cursor
+}
+
+// Err returns the error, if any, which stopped the iteration.
+func (it *MarketTradeIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,159 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// SlippageError is returned by MarketOrder when the requested volume cannot be
+// filled from the order book within the maximum slippage.
+type SlippageError struct {
+	Volume    AmountWhole
+	Available AmountWhole
+	Limit     AmountWhole
+}
+
+func (e *SlippageError) Error() string {
+	return fmt.Sprintf("Only %f of %f is available within the slippage limit price of %f",
+		e.Available.ToAmountDecimal(), e.Volume.ToAmountDecimal(), e.Limit.ToAmountDecimal())
+}
+
+// MarketOrderResult describes the expected and actual outcome of MarketOrder.
+type MarketOrderResult struct {
+	// BestPrice is the best price on the opposite side of the book.
+	BestPrice AmountWhole
+
+	// LimitPrice is the price of the marketable limit order, being the best
+	// price moved by the maximum slippage.
+	LimitPrice AmountWhole
+
+	// ExpectedPrice is the average price the volume is expected to fill at,
+	// from the order book.
+	ExpectedPrice AmountWhole
+
+	// ExpectedSlippage is the fraction by which the expected price is worse
+	// than the best price.
+	ExpectedSlippage float64
+
+	// Order is the response from placing the order.
+	Order *OrderCreateResponse
+
+	// Filled is the volume matched when the order was checked after placing.
+	Filled AmountWhole
+
+	// ActualPrice is the average price of the volume matched, or zero if none
+	// was matched.
+	ActualPrice AmountWhole
+}
+
+// MarketOrder safely emulates a market order. The order book is used to work
+// out the average price the volume is expected to fill at, and the order is
+// refused with a *SlippageError if it cannot be filled within maxSlippage (a
+// fraction, such as 0.01 for 1%) of the best price. Otherwise a marketable
+// limit order is placed at the slippage limit price, so that it can never fill
+// at a worse price even if the book moves.
+//
+// The order is checked once after placing to report the actual average fill
+// price. Any volume not matched immediately remains on the book.
+func (c *Client) MarketOrder(currency Currency, instrument Instrument, side OrderSide, volume AmountWhole, maxSlippage float64) (*MarketOrderResult, error) {
+	if side != Ask && side != Bid {
+		return nil, fmt.Errorf("Unknown order side %q", side)
+	}
+
+	if volume <= 0 {
+		return nil, errors.New("Volume must be greater than zero")
+	}
+
+	if maxSlippage < 0 {
+		return nil, errors.New("Maximum slippage must not be negative")
+	}
+
+	book, err := c.MarketOrderbook(instrument, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	// A bid fills against the asks, and an ask against the bids.
+	levels := book.Asks
+	if side == Ask {
+		levels = book.Bids
+	}
+
+	best, ok := levels.Best()
+	if !ok {
+		return nil, fmt.Errorf("The %s/%s order book is empty", instrument, currency)
+	}
+
+	res := &MarketOrderResult{
+		BestPrice:  best.Price,
+		LimitPrice: slippageLimit(currency, side, best.Price, maxSlippage),
+	}
+
+	var filled, notional AmountWhole
+	levels.Each(func(i int, pl PriceLevel, cumulative AmountWhole) bool {
+		if (side == Bid && pl.Price > res.LimitPrice) || (side == Ask && pl.Price < res.LimitPrice) {
+			return false
+		}
+
+		take := pl.Volume
+		if filled+take > volume {
+			take = volume - filled
+		}
+
+		filled += take
+		notional += pl.Price.Mul(take)
+
+		return filled < volume
+	})
+
+	if filled < volume {
+		return res, &SlippageError{Volume: volume, Available: filled, Limit: res.LimitPrice}
+	}
+
+	res.ExpectedPrice = notional.Div(volume)
+	res.ExpectedSlippage = float64(res.ExpectedPrice-best.Price) / float64(best.Price)
+	if side == Ask {
+		res.ExpectedSlippage = -res.ExpectedSlippage
+	}
+
+	res.Order, err = c.OrderCreate(currency, instrument, res.LimitPrice, volume, side, Limit, "")
+	if err != nil {
+		return res, err
+	}
+
+	if !res.Order.Success {
+		return res, fmt.Errorf("Failed to place order (%d: %s)", res.Order.ErrorCode, res.Order.ErrorMessage)
+	}
+
+	o, err := c.orderDetail(res.Order.ID)
+	if err != nil {
+		return res, err
+	}
+
+	var actual AmountWhole
+	for _, t := range o.Trades {
+		res.Filled += t.Volume
+		actual += t.Price.Mul(t.Volume)
+	}
+	res.ActualPrice = actual.Div(res.Filled)
+
+	return res, nil
+}
+
+// slippageLimit returns the worst price an order may fill at, moving the best
+// price against the order by the maximum slippage. AUD prices are rounded to
+// whole cents in the order's favour.
+func slippageLimit(currency Currency, side OrderSide, best AmountWhole, maxSlippage float64) AmountWhole {
+	offset := AmountWhole(float64(best) * maxSlippage)
+
+	limit := best + offset
+	if side == Ask {
+		limit = best - offset
+	}
+
+	if currency == CurrencyAUD {
+		limit = roundAUDPrice(limit, side)
+	}
+
+	return limit
+}
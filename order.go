@@ -2,7 +2,9 @@ package btcmarkets
 
 import (
 	"errors"
-	"math"
+	"fmt"
+	"sort"
+	"time"
 )
 
 /*
@@ -40,6 +42,9 @@ type OrderCreateResponse struct {
 }
 
 // OrderCreate implements the POST /order/create endpoint.
+//
+// Market orders are placed without any protection against slippage, use
+// MarketOrder to cap the price a market order may fill at.
 func (c *Client) OrderCreate(
 	currency Currency,
 	instrument Instrument,
@@ -68,8 +73,9 @@ func (c *Client) OrderCreate(
 		rec.Price = 99999900000000
 	}
 
-	// An AUD currency amount is not allowed to have more than two decimal places
-	if rec.Currency == CurrencyAUD && math.Mod(float64(rec.Price), 1000000) != 0 {
+	// An AUD currency amount is not allowed to have more than two decimal places.
+	// Market orders are exempt, as their price is a placeholder set above.
+	if rec.Currency == CurrencyAUD && rec.OrderType != Market && rec.Price%audPriceStep != 0 {
 		// If the third degree decimal onwards has a value, then return 0 (error)
 		return nil, errors.New("AUD currency only allows two decimal places")
 	}
@@ -84,6 +90,22 @@ func (c *Client) OrderCreate(
 	return ocr, nil
 }
 
+// audPriceStep is the smallest AUD price increment, as AUD prices only allow two
+// decimal places.
+const audPriceStep AmountWhole = 1000000
+
+// roundAUDPrice rounds a price to whole cents so that OrderCreate accepts it in
+// an AUD market. A bid is rounded down and an ask up, so that the order is
+// never priced worse for the account than requested.
+func roundAUDPrice(price AmountWhole, side OrderSide) AmountWhole {
+	rounded := price / audPriceStep * audPriceStep
+	if side == Ask && rounded < price {
+		rounded += audPriceStep
+	}
+
+	return rounded
+}
+
 // OrderCancelResponse represents the JSON data structure returned from
 // the POST /order/cancel endpoint.
 type OrderCancelResponse struct {
@@ -184,13 +206,47 @@ func (c *Client) OrderOpen(currency Currency, instrument Instrument, limit int,
 	return oor, nil
 }
 
+// OrderTradeHistoryRequest represents the JSON data structure sent to
+// the POST /order/trade/history endpoint. It shares the same data
+// structure as OrderHistoryRequest
+type OrderTradeHistoryRequest struct {
+	OrderHistoryRequest
+}
+
+// OrderTradeHistoryResponse represents the JSON data structure returned from
+// the POST /order/trade/history endpoint.
+type OrderTradeHistoryResponse struct {
+	Success      bool                 `json:"success"`
+	ErrorCode    string               `json:"errorCode"`
+	ErrorMessage string               `json:"errorMessage"`
+	Trades       []OrderTradeDataItem `json:"trades"`
+}
+
+// OrderTradeHistory implements the POST /order/trade/history API endpoint
+func (c *Client) OrderTradeHistory(currency Currency, instrument Instrument, limit int, since TradeID) (*OrderTradeHistoryResponse, error) {
+	othReq := &OrderTradeHistoryRequest{}
+	othReq.Currency = currency
+	othReq.Instrument = instrument
+	othReq.Limit = limit
+	othReq.Since = OrderID(since)
+
+	othRes := &OrderTradeHistoryResponse{}
+
+	err := c.Post("/order/trade/history", othReq, othRes, rateLimit10)
+	if err != nil {
+		return nil, err
+	}
+
+	return othRes, nil
+}
+
 // OrderDetailResponse represents the JSON data structure returned from
 // the POST /order/detail endpoint.
 type OrderDetailResponse struct {
 	Success      bool            `json:"success"`
 	ErrorCode    string          `json:"errorCode"`
 	ErrorMessage string          `json:"errorMessage"`
-	Orders       []OrderDataItem `josn:"orders"`
+	Orders       []OrderDataItem `json:"orders"`
 }
 
 // OrderDetail implements the POST /order/detail API endpoint
@@ -242,30 +298,30 @@ type OrderStatus string
 
 const (
 	// OrderStatusNew is an order which is created but has not yet been placed
-	OrderStatusNew = "New"
+	OrderStatusNew OrderStatus = "New"
 
 	// OrderStatusPlaced is a placed order which is unfilled
-	OrderStatusPlaced = "Placed"
+	OrderStatusPlaced OrderStatus = "Placed"
 
 	// OrderStatusFailed is an order which has failed
-	OrderStatusFailed = "Failed"
+	OrderStatusFailed OrderStatus = "Failed"
 
 	// OrderStatusError is an order which has failed due to an error
-	OrderStatusError = "Error"
+	OrderStatusError OrderStatus = "Error"
 
 	// OrderStatusCancelled is an order cancelled by the client
-	OrderStatusCancelled = "Cancelled"
+	OrderStatusCancelled OrderStatus = "Cancelled"
 
 	// OrderStatusPartiallyCancelled is an order that has been partially
 	// completed, but cancelled before fully matched / completed
-	OrderStatusPartiallyCancelled = "Partially Cancelled"
+	OrderStatusPartiallyCancelled OrderStatus = "Partially Cancelled"
 
 	// OrderStatusFullyMatched is a completed successful order
-	OrderStatusFullyMatched = "Fully Matched"
+	OrderStatusFullyMatched OrderStatus = "Fully Matched"
 
 	// OrderStatusPartiallyMatched is a partially completed order for which some
 	// of the instrument has been traded, but not enough to complete the order
-	OrderStatusPartiallyMatched = "Partially Matched"
+	OrderStatusPartiallyMatched OrderStatus = "Partially Matched"
 )
 
 // OrderID is an integer representing the returned ID of a created order
@@ -287,7 +343,13 @@ type OrderDataItem struct {
 	Trades       []OrderTradeDataItem `json:"trades"`
 }
 
-// OrderTradeDataItem is the data structure that represents a single trade
+// CreatedTime returns the creation time of the order.
+func (odi *OrderDataItem) CreatedTime() time.Time {
+	return millisTime(odi.Created)
+}
+
+// OrderTradeDataItem is the data structure that represents a single trade.
+// The side and order ID are only populated by the trade history endpoint.
 type OrderTradeDataItem struct {
 	TradeID     TradeID     `json:"id"`
 	Created     int64       `json:"creationTime"`
@@ -295,4 +357,213 @@ type OrderTradeDataItem struct {
 	Price       AmountWhole `json:"price"`
 	Volume      AmountWhole `json:"volume"`
 	Fee         AmountWhole `json:"fee"`
+	OrderSide   OrderSide   `json:"side"`
+	OrderID     OrderID     `json:"orderId"`
+}
+
+// CreatedTime returns the time the trade occurred.
+func (otdi *OrderTradeDataItem) CreatedTime() time.Time {
+	return millisTime(otdi.Created)
+}
+
+// millisTime converts a timestamp in milliseconds since the Unix epoch, as used
+// by the order endpoints, into a time.
+func millisTime(millis int64) time.Time {
+	return time.Unix(millis/1000, (millis%1000)*int64(time.Millisecond))
+}
+
+// orderPageLimit is the number of orders or trades requested per page by the
+// order iterators, being the maximum allowed by the API.
+const orderPageLimit = 200
+
+// OrderIterator walks a list of orders oldest first, one page at a time. Pages
+// are requested through the client as they are needed, so they are subject to
+// the client's rate limiting. Stopping before the iterator is exhausted avoids
+// requesting any further pages.
+//
+// The iterator is not concurrency safe.
+type OrderIterator struct {
+	fetch   func(since OrderID) (*OrderDetailResponse, error)
+	cursor  OrderID
+	page    []OrderDataItem
+	current OrderDataItem
+	err     error
+	done    bool
+}
+
+// OrderHistoryAll returns an iterator over every order in the market's order
+// history which was created after the supplied order ID.
+//
+//	it := cl.OrderHistoryAll(btcmarkets.CurrencyAUD, btcmarkets.InstrumentBitcoin, 0)
+//	for it.Next() {
+//		order := it.Order()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+func (c *Client) OrderHistoryAll(currency Currency, instrument Instrument, since OrderID) *OrderIterator {
+	return &OrderIterator{
+		fetch: func(since OrderID) (*OrderDetailResponse, error) {
+			ohRes, err := c.OrderHistory(currency, instrument, orderPageLimit, since)
+			if err != nil {
+				return nil, err
+			}
+
+			return &ohRes.OrderDetailResponse, nil
+		},
+		cursor: since,
+	}
+}
+
+// OrderOpenAll returns an iterator over every open order in the market which
+// was created after the supplied order ID.
+func (c *Client) OrderOpenAll(currency Currency, instrument Instrument, since OrderID) *OrderIterator {
+	return &OrderIterator{
+		fetch: func(since OrderID) (*OrderDetailResponse, error) {
+			ooRes, err := c.OrderOpen(currency, instrument, orderPageLimit, since)
+			if err != nil {
+				return nil, err
+			}
+
+			return &ooRes.OrderDetailResponse, nil
+		},
+		cursor: since,
+	}
+}
+
+// Next advances the iterator to the next order, fetching a new page when
+// required. It returns false when the orders are exhausted or an error occurs.
+func (it *OrderIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 && !it.done {
+		odr, err := it.fetch(it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if !odr.Success {
+			it.err = fmt.Errorf("Failed to fetch orders (%s: %s)", odr.ErrorCode, odr.ErrorMessage)
+			return false
+		}
+
+		it.page = make([]OrderDataItem, 0, len(odr.Orders))
+		for _, o := range odr.Orders {
+			if o.OrderID > it.cursor {
+				it.page = append(it.page, o)
+			}
+		}
+		sort.Slice(it.page, func(i, j int) bool { return it.page[i].OrderID < it.page[j].OrderID })
+
+		it.done = len(odr.Orders) < orderPageLimit || len(it.page) == 0
+	}
+
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	it.cursor = it.current.OrderID
+
+	return true
+}
+
+// Order returns the order the iterator is currently positioned on.
+func (it *OrderIterator) Order() OrderDataItem {
+	return it.current
+}
+
+// Checkpoint returns the ID of the last order returned by the iterator.
+func (it *OrderIterator) Checkpoint() OrderID {
+	return it.cursor
+}
+
+// Err returns the error, if any, which stopped the iteration.
+func (it *OrderIterator) Err() error {
+	return it.err
+}
+
+// OrderTradeIterator walks the account's trade history (fills) for a market
+// oldest first, one page at a time. It behaves in the same way as OrderIterator.
+type OrderTradeIterator struct {
+	c          *Client
+	currency   Currency
+	instrument Instrument
+	cursor     TradeID
+	page       []OrderTradeDataItem
+	current    OrderTradeDataItem
+	err        error
+	done       bool
+}
+
+// OrderTradeHistoryAll returns an iterator over every trade in the market's
+// trade history which occurred after the supplied trade ID.
+func (c *Client) OrderTradeHistoryAll(currency Currency, instrument Instrument, since TradeID) *OrderTradeIterator {
+	return &OrderTradeIterator{
+		c:          c,
+		currency:   currency,
+		instrument: instrument,
+		cursor:     since,
+	}
+}
+
+// Next advances the iterator to the next trade, fetching a new page when
+// required. It returns false when the trades are exhausted or an error occurs.
+func (it *OrderTradeIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	if len(it.page) == 0 && !it.done {
+		othRes, err := it.c.OrderTradeHistory(it.currency, it.instrument, orderPageLimit, it.cursor)
+		if err != nil {
+			it.err = err
+			return false
+		}
+
+		if !othRes.Success {
+			it.err = fmt.Errorf("Failed to fetch trade history (%s: %s)", othRes.ErrorCode, othRes.ErrorMessage)
+			return false
+		}
+
+		it.page = make([]OrderTradeDataItem, 0, len(othRes.Trades))
+		for _, t := range othRes.Trades {
+			if t.TradeID > it.cursor {
+				it.page = append(it.page, t)
+			}
+		}
+		sort.Slice(it.page, func(i, j int) bool { return it.page[i].TradeID < it.page[j].TradeID })
+
+		it.done = len(othRes.Trades) < orderPageLimit || len(it.page) == 0
+	}
+
+	if len(it.page) == 0 {
+		return false
+	}
+
+	it.current = it.page[0]
+	it.page = it.page[1:]
+	it.cursor = it.current.TradeID
+
+	return true
+}
+
+// Trade returns the trade the iterator is currently positioned on.
+func (it *OrderTradeIterator) Trade() OrderTradeDataItem {
+	return it.current
+}
+
+// Checkpoint returns the ID of the last trade returned by the iterator.
+func (it *OrderTradeIterator) Checkpoint() TradeID {
+	return it.cursor
+}
+
+// Err returns the error, if any, which stopped the iteration.
+func (it *OrderTradeIterator) Err() error {
+	return it.err
 }
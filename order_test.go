@@ -0,0 +1,56 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestOrderCreateAUDMarketBid(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req OrderCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatal(err)
+		}
+		if req.OrderType != Market || req.OrderSide != Bid {
+			t.Errorf("request = %+v, want a market bid", req)
+		}
+
+		writeJSON(t, w, OrderCreateResponse{Success: true, ID: 7, ClientRequestID: req.ClientRequestID})
+	})
+
+	res, err := c.OrderCreate(CurrencyAUD, InstrumentBitcoin, 0, 100000000, Bid, Market, "t1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Success || res.ID != 7 {
+		t.Errorf("response = %+v", res)
+	}
+}
+
+func TestOrderCreateAUDPrecision(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("no request should be sent for an AUD price with more than two decimal places")
+	})
+
+	if _, err := c.OrderCreate(CurrencyAUD, InstrumentBitcoin, 1000012345, 100000000, Bid, Limit, "t1"); err == nil {
+		t.Fatal("OrderCreate error = nil, want an error")
+	}
+}
+
+func TestRoundAUDPrice(t *testing.T) {
+	for _, tc := range []struct {
+		price AmountWhole
+		side  OrderSide
+		want  AmountWhole
+	}{
+		{1000012345, Bid, 1000000000},
+		{1000012345, Ask, 1001000000},
+		{1001000000, Bid, 1001000000},
+		{1001000000, Ask, 1001000000},
+	} {
+		if got := roundAUDPrice(tc.price, tc.side); got != tc.want {
+			t.Errorf("roundAUDPrice(%d, %s) = %d, want %d", tc.price, tc.side, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// PriceLevel is a single level of an order book, being the total volume
+// available at a price.
+type PriceLevel struct {
+	Price  AmountWhole
+	Volume AmountWhole
+}
+
+// UnmarshalJSON decodes a price level from the [price, volume] array form
+// returned by the API. The numbers are decoded exactly, rather than through
+// a float64.
+func (pl *PriceLevel) UnmarshalJSON(data []byte) error {
+	var raw []json.Number
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("Failed to decode price level (%s)", err.Error())
+	}
+
+	if len(raw) != 2 {
+		return fmt.Errorf("Price level should have 2 elements, got %d", len(raw))
+	}
+
+	price, err := ParseAmountWhole(raw[0].String())
+	if err != nil {
+		return err
+	}
+
+	volume, err := ParseAmountWhole(raw[1].String())
+	if err != nil {
+		return err
+	}
+
+	pl.Price = price
+	pl.Volume = volume
+
+	return nil
+}
+
+// MarshalJSON encodes a price level back into the [price, volume] array form.
+func (pl PriceLevel) MarshalJSON() ([]byte, error) {
+	return json.Marshal([]AmountDecimal{pl.Price.ToAmountDecimal(), pl.Volume.ToAmountDecimal()})
+}
+
+// Notional returns the value of the price level in the market currency
+// (price multiplied by volume).
+func (pl PriceLevel) Notional() AmountWhole {
+	return pl.Price.Mul(pl.Volume)
+}
+
+// String is a helper method for displaying a price level in human-readable format.
+func (pl PriceLevel) String() string {
+	return fmt.Sprintf("%f at %f", pl.Volume.ToAmountDecimal(), pl.Price.ToAmountDecimal())
+}
+
+// PriceLevels is one side of an order book, ordered from the best price
+// outwards (highest first for bids, lowest first for asks).
+type PriceLevels []PriceLevel
+
+// Best returns the best price level on this side of the book, or false if
+// the side is empty.
+func (pls PriceLevels) Best() (PriceLevel, bool) {
+	if len(pls) == 0 {
+		return PriceLevel{}, false
+	}
+
+	return pls[0], true
+}
+
+// Volume returns the total volume available on this side of the book.
+func (pls PriceLevels) Volume() AmountWhole {
+	var total AmountWhole
+	for _, pl := range pls {
+		total += pl.Volume
+	}
+
+	return total
+}
+
+// CumulativeVolume returns the running total of volume at each level, so
+// that element i is the volume available at levels 0 through i.
+func (pls PriceLevels) CumulativeVolume() []AmountWhole {
+	cumulative := make([]AmountWhole, len(pls))
+
+	var total AmountWhole
+	for i, pl := range pls {
+		total += pl.Volume
+		cumulative[i] = total
+	}
+
+	return cumulative
+}
+
+// DepthAt returns the cumulative volume and notional available at prices
+// at least as good as the provided price. For bids that is every level at or
+// above the price, and for asks every level at or below it.
+func (pls PriceLevels) DepthAt(side OrderSide, price AmountWhole) (volume, notional AmountWhole) {
+	pls.Each(func(i int, pl PriceLevel, cumulative AmountWhole) bool {
+		if (side == Bid && pl.Price < price) || (side == Ask && pl.Price > price) {
+			return false
+		}
+
+		volume = cumulative
+		notional += pl.Notional()
+
+		return true
+	})
+
+	return volume, notional
+}
+
+// Each calls fn for every level from the best price outwards, along with the
+// cumulative volume up to and including that level. Iteration stops early if
+// fn returns false.
+func (pls PriceLevels) Each(fn func(i int, pl PriceLevel, cumulative AmountWhole) bool) {
+	var total AmountWhole
+	for i, pl := range pls {
+		total += pl.Volume
+		if !fn(i, pl, total) {
+			return
+		}
+	}
+}
+
+// Levels returns the side of the order book that an order on the provided
+// side would rest on, being the bids for a Bid and the asks for an Ask.
+func (mor *MarketOrderbookResponse) Levels(side OrderSide) PriceLevels {
+	if side == Ask {
+		return mor.Asks
+	}
+
+	return mor.Bids
+}
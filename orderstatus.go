@@ -0,0 +1,103 @@
+package btcmarkets
+
+import (
+	"fmt"
+)
+
+// orderStatusTransitions is the table of direct transitions between order
+// statuses. Statuses without an entry are terminal.
+var orderStatusTransitions = map[OrderStatus][]OrderStatus{
+	OrderStatusNew: {
+		OrderStatusPlaced,
+		OrderStatusPartiallyMatched,
+		OrderStatusFullyMatched,
+		OrderStatusCancelled,
+		OrderStatusFailed,
+		OrderStatusError,
+	},
+	OrderStatusPlaced: {
+		OrderStatusPartiallyMatched,
+		OrderStatusFullyMatched,
+		OrderStatusCancelled,
+		OrderStatusError,
+	},
+	OrderStatusPartiallyMatched: {
+		OrderStatusFullyMatched,
+		OrderStatusPartiallyCancelled,
+		OrderStatusError,
+	},
+}
+
+// IsValid reports whether the status is one of the known order statuses.
+func (s OrderStatus) IsValid() bool {
+	switch s {
+	case OrderStatusNew, OrderStatusPlaced, OrderStatusFailed, OrderStatusError,
+		OrderStatusCancelled, OrderStatusPartiallyCancelled, OrderStatusFullyMatched,
+		OrderStatusPartiallyMatched:
+		return true
+	}
+
+	return false
+}
+
+// IsOpen reports whether an order with the status may still be matched.
+func (s OrderStatus) IsOpen() bool {
+	return s == OrderStatusNew || s == OrderStatusPlaced || s == OrderStatusPartiallyMatched
+}
+
+// IsTerminal reports whether an order with the status will never change again.
+func (s OrderStatus) IsTerminal() bool {
+	return s.IsValid() && !s.IsOpen()
+}
+
+// CanTransition reports whether an order may move from the status to the
+// provided status. As orders are observed by polling, intermediate statuses
+// may be missed, so any status reachable through the transition table is
+// allowed, as is remaining in the same status.
+func (s OrderStatus) CanTransition(to OrderStatus) bool {
+	if s == to {
+		return true
+	}
+
+	visited := map[OrderStatus]bool{s: true}
+	queue := []OrderStatus{s}
+	for len(queue) > 0 {
+		next := queue[0]
+		queue = queue[1:]
+
+		for _, candidate := range orderStatusTransitions[next] {
+			if candidate == to {
+				return true
+			}
+
+			if !visited[candidate] {
+				visited[candidate] = true
+				queue = append(queue, candidate)
+			}
+		}
+	}
+
+	return false
+}
+
+// ValidateTransition returns an *OrderTransitionError if an order may not move
+// between the two statuses.
+func ValidateTransition(id OrderID, from, to OrderStatus) error {
+	if !from.CanTransition(to) {
+		return &OrderTransitionError{OrderID: id, From: from, To: to}
+	}
+
+	return nil
+}
+
+// OrderTransitionError describes an impossible change of status of an order,
+// such as one reported by the API moving from Fully Matched back to Placed.
+type OrderTransitionError struct {
+	OrderID OrderID
+	From    OrderStatus
+	To      OrderStatus
+}
+
+func (e *OrderTransitionError) Error() string {
+	return fmt.Sprintf("Order %d cannot change status from %q to %q", e.OrderID, e.From, e.To)
+}
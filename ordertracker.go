@@ -0,0 +1,207 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// orderBatchSize is the maximum number of order IDs sent in a single
+	// request to the endpoints which accept multiple orders.
+	orderBatchSize = 20
+
+	// orderTrackerBatchInterval is the minimum time between order detail
+	// requests made by an OrderTracker.
+	orderTrackerBatchInterval = 2 * time.Second
+
+	// orderTrackerEventBuffer is the number of events buffered by an OrderTracker.
+	orderTrackerEventBuffer = 64
+)
+
+// OrderEvent is sent by an OrderTracker when a tracked order changes status or
+// receives new fills. Previous is empty the first time an order is seen. If
+// the API reports an impossible status transition, Err is an
+// *OrderTransitionError.
+type OrderEvent struct {
+	Order     OrderDataItem
+	Previous  OrderStatus
+	NewTrades []OrderTradeDataItem
+	Err       error
+}
+
+// trackedOrder is the last known state of an order tracked by an OrderTracker.
+type trackedOrder struct {
+	status OrderStatus
+	trades map[TradeID]bool
+	done   chan OrderDataItem
+}
+
+// OrderTracker watches orders until they reach a terminal status, polling the
+// details of every tracked order in batched OrderDetail requests. It is
+// concurrency safe.
+//
+// Events are delivered on the Events channel, which must be drained while the
+// tracker is running.
+type OrderTracker struct {
+	c        *Client
+	interval time.Duration
+
+	mu     sync.Mutex
+	orders map[OrderID]*trackedOrder
+	events chan OrderEvent
+	poller poller
+}
+
+// NewOrderTracker constructs a new OrderTracker which polls tracked orders once
+// per interval.
+func NewOrderTracker(c *Client, interval time.Duration) *OrderTracker {
+	if interval < orderTrackerBatchInterval {
+		interval = orderTrackerBatchInterval
+	}
+
+	return &OrderTracker{
+		c:        c,
+		interval: interval,
+		orders:   make(map[OrderID]*trackedOrder),
+		events:   make(chan OrderEvent, orderTrackerEventBuffer),
+	}
+}
+
+// Track starts tracking an order. The returned channel receives the order once
+// it reaches a terminal status, and is then closed. Tracking an order which is
+// already tracked returns the existing channel.
+func (ot *OrderTracker) Track(id OrderID) <-chan OrderDataItem {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	if to, ok := ot.orders[id]; ok {
+		return to.done
+	}
+
+	to := &trackedOrder{
+		trades: make(map[TradeID]bool),
+		done:   make(chan OrderDataItem, 1),
+	}
+	ot.orders[id] = to
+
+	return to.done
+}
+
+// Untrack stops tracking an order, closing its channel without a value.
+func (ot *OrderTracker) Untrack(id OrderID) {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	if to, ok := ot.orders[id]; ok {
+		delete(ot.orders, id)
+		close(to.done)
+	}
+}
+
+// Tracking returns the number of orders currently being tracked.
+func (ot *OrderTracker) Tracking() int {
+	ot.mu.Lock()
+	defer ot.mu.Unlock()
+
+	return len(ot.orders)
+}
+
+// Events returns the channel on which order events are delivered.
+func (ot *OrderTracker) Events() <-chan OrderEvent {
+	return ot.events
+}
+
+// Start begins polling in the background. It does nothing if the tracker is
+// already running.
+func (ot *OrderTracker) Start() {
+	ot.poller.start(ot.interval, true, func(stop <-chan struct{}) {
+		for _, ev := range ot.poll() {
+			select {
+			case ot.events <- ev:
+			case <-stop:
+				return
+			}
+		}
+	})
+}
+
+// Stop stops polling and waits for any poll in progress to finish. Tracked
+// orders remain tracked so that the tracker can be started again.
+func (ot *OrderTracker) Stop() {
+	ot.poller.halt()
+}
+
+// poll fetches the details of every tracked order and returns the resulting
+// events.
+func (ot *OrderTracker) poll() []OrderEvent {
+	ot.mu.Lock()
+	ids := make([]OrderID, 0, len(ot.orders))
+	for id := range ot.orders {
+		ids = append(ids, id)
+	}
+	ot.mu.Unlock()
+
+	var events []OrderEvent
+	for start := 0; start < len(ids); start += orderBatchSize {
+		end := start + orderBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+
+		odr, err := ot.c.OrderDetail(ids[start:end]...)
+		if err == nil && !odr.Success {
+			err = fmt.Errorf("Failed to fetch order details (%s: %s)", odr.ErrorCode, odr.ErrorMessage)
+		}
+		if err != nil {
+			events = append(events, OrderEvent{Err: err})
+			continue
+		}
+
+		ot.mu.Lock()
+		for _, o := range odr.Orders {
+			if ev, ok := ot.update(o); ok {
+				events = append(events, ev)
+			}
+		}
+		ot.mu.Unlock()
+	}
+
+	return events
+}
+
+// update applies the latest details of an order, returning an event if the
+// order changed. It must be called with the lock held.
+func (ot *OrderTracker) update(o OrderDataItem) (OrderEvent, bool) {
+	to, ok := ot.orders[o.OrderID]
+	if !ok {
+		return OrderEvent{}, false
+	}
+
+	ev := OrderEvent{
+		Order:    o,
+		Previous: to.status,
+	}
+
+	for _, t := range o.Trades {
+		if !to.trades[t.TradeID] {
+			to.trades[t.TradeID] = true
+			ev.NewTrades = append(ev.NewTrades, t)
+		}
+	}
+
+	if to.status != "" && !to.status.CanTransition(o.Status) {
+		ev.Err = &OrderTransitionError{OrderID: o.OrderID, From: to.status, To: o.Status}
+	}
+
+	changed := o.Status != to.status || len(ev.NewTrades) > 0 || ev.Err != nil
+	to.status = o.Status
+
+	if o.Status.IsTerminal() {
+		delete(ot.orders, o.OrderID)
+		to.done <- o
+		close(to.done)
+	}
+
+	return ev, changed
+}
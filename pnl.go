@@ -0,0 +1,428 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CostMethod is the method used to match sales against purchases when
+// accounting for profit and loss.
+type CostMethod string
+
+// Enumerated cost methods.
+const (
+	// CostFIFO matches a sale against the earliest purchases still held.
+	CostFIFO CostMethod = "FIFO"
+
+	// CostLIFO matches a sale against the latest purchases still held.
+	CostLIFO CostMethod = "LIFO"
+
+	// CostAverage pools purchases, so that a sale is matched at the average
+	// cost of the holding.
+	CostAverage CostMethod = "Average"
+)
+
+// Fill is a single trade made by the account, in a form suitable for
+// accounting.
+type Fill struct {
+	Pair    MarketPair
+	Side    OrderSide
+	TradeID TradeID
+	OrderID OrderID
+	Time    time.Time
+	Price   AmountWhole
+	Volume  AmountWhole
+	Fee     AmountWhole
+}
+
+// NewFill converts a trade from the trade history of a market into a Fill. The
+// trade's side must be populated, as it is by OrderTradeHistory.
+func NewFill(currency Currency, instrument Instrument, t OrderTradeDataItem) Fill {
+	return Fill{
+		Pair:    MarketPair{Instrument: instrument, Currency: currency},
+		Side:    t.OrderSide,
+		TradeID: t.TradeID,
+		OrderID: t.OrderID,
+		Time:    t.CreatedTime(),
+		Price:   t.Price,
+		Volume:  t.Volume,
+		Fee:     t.Fee,
+	}
+}
+
+// FillsFromOrder converts the trades of an order into Fills.
+func FillsFromOrder(o OrderDataItem) []Fill {
+	fills := make([]Fill, len(o.Trades))
+	for i, t := range o.Trades {
+		fills[i] = NewFill(o.Currency, o.Instrument, t)
+		fills[i].Side = o.OrderSide
+		fills[i].OrderID = o.OrderID
+	}
+
+	return fills
+}
+
+// Lot is a holding acquired by a purchase which has not yet been sold. Cost
+// includes the purchase's fee.
+type Lot struct {
+	Pair     MarketPair
+	TradeID  TradeID
+	Acquired time.Time
+	Volume   AmountWhole
+	Cost     AmountWhole
+}
+
+// Disposal is a sale matched against a single lot. CostBase includes the
+// purchase fee, and Proceeds are net of the sale fee, both in proportion to the
+// volume matched.
+type Disposal struct {
+	Pair        MarketPair
+	BuyTradeID  TradeID
+	SellTradeID TradeID
+	Acquired    time.Time
+	Disposed    time.Time
+	Volume      AmountWhole
+	CostBase    AmountWhole
+	Proceeds    AmountWhole
+}
+
+// Gain returns the profit (or loss, when negative) of the disposal.
+func (d Disposal) Gain() AmountWhole {
+	return d.Proceeds - d.CostBase
+}
+
+// MatchLots matches sales against purchases using the cost method, returning
+// every disposal along with the lots still held. Fills are processed in time
+// order, and a fill appearing more than once is only counted once. Opening lots
+// are holdings acquired outside the fills, such as an instrument deposited into
+// the account, and are held from before the first fill. Selling more than is
+// held is an error.
+//
+// With CostAverage each market's holding is a single pooled lot, dated from
+// the earliest purchase still in the pool.
+func MatchLots(fills []Fill, method CostMethod, opening ...Lot) ([]Disposal, []Lot, error) {
+	disposals, lots, unmatched, err := matchLots(fills, method, opening)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if len(unmatched) > 0 {
+		f := unmatched[0]
+		return nil, nil, fmt.Errorf("Sale of %f %s in trade %d exceeds the holding by %f",
+			f.Volume.ToAmountDecimal(), f.Pair.Instrument, f.TradeID, f.Unmatched.ToAmountDecimal())
+	}
+
+	return disposals, lots, nil
+}
+
+// unmatchedSale is a sale of more than was held, and the volume of it which
+// could not be matched against a lot.
+type unmatchedSale struct {
+	Fill
+	Unmatched AmountWhole
+}
+
+// matchLots is MatchLots, returning the sales of more than was held instead of
+// failing on them. Only the held part of such a sale is disposed of.
+func matchLots(fills []Fill, method CostMethod, opening []Lot) ([]Disposal, []Lot, []unmatchedSale, error) {
+	switch method {
+	case CostFIFO, CostLIFO, CostAverage:
+	default:
+		return nil, nil, nil, fmt.Errorf("Unknown cost method %q", method)
+	}
+
+	var (
+		disposals []Disposal
+		unmatched []unmatchedSale
+	)
+	held := make(map[MarketPair][]Lot)
+
+	acquire := func(lot Lot) {
+		lots := held[lot.Pair]
+		if method == CostAverage && len(lots) > 0 {
+			lots[0].Volume += lot.Volume
+			lots[0].Cost += lot.Cost
+		} else {
+			lots = append(lots, lot)
+		}
+		held[lot.Pair] = lots
+	}
+
+	opening = append([]Lot(nil), opening...)
+	sort.SliceStable(opening, func(i, j int) bool { return opening[i].Acquired.Before(opening[j].Acquired) })
+	for _, lot := range opening {
+		if lot.Volume > 0 {
+			acquire(lot)
+		}
+	}
+
+	for _, f := range sortFills(fills) {
+		switch f.Side {
+		case Bid:
+			acquire(Lot{
+				Pair:     f.Pair,
+				TradeID:  f.TradeID,
+				Acquired: f.Time,
+				Volume:   f.Volume,
+				Cost:     f.Price.Mul(f.Volume) + f.Fee,
+			})
+
+		case Ask:
+			lots := held[f.Pair]
+			proceeds := f.Price.Mul(f.Volume) - f.Fee
+			remaining := f.Volume
+			for remaining > 0 && len(lots) > 0 {
+				i := 0
+				if method == CostLIFO {
+					i = len(lots) - 1
+				}
+
+				take := lots[i].Volume
+				if take > remaining {
+					take = remaining
+				}
+
+				cost := scaleAmount(lots[i].Cost, take, lots[i].Volume)
+				disposals = append(disposals, Disposal{
+					Pair:        f.Pair,
+					BuyTradeID:  lots[i].TradeID,
+					SellTradeID: f.TradeID,
+					Acquired:    lots[i].Acquired,
+					Disposed:    f.Time,
+					Volume:      take,
+					CostBase:    cost,
+					Proceeds:    scaleAmount(proceeds, take, f.Volume),
+				})
+
+				lots[i].Volume -= take
+				lots[i].Cost -= cost
+				remaining -= take
+
+				if lots[i].Volume == 0 {
+					lots = append(lots[:i], lots[i+1:]...)
+				}
+			}
+			held[f.Pair] = lots
+
+			if remaining > 0 {
+				unmatched = append(unmatched, unmatchedSale{Fill: f, Unmatched: remaining})
+			}
+
+		default:
+			return nil, nil, nil, fmt.Errorf("Trade %d has unknown side %q", f.TradeID, f.Side)
+		}
+	}
+
+	var open []Lot
+	for _, lots := range held {
+		open = append(open, lots...)
+	}
+	sort.SliceStable(open, func(i, j int) bool { return open[i].Acquired.Before(open[j].Acquired) })
+
+	return disposals, open, unmatched, nil
+}
+
+// sortFills returns the fills without duplicates, ordered by time and then
+// trade ID.
+func sortFills(fills []Fill) []Fill {
+	type key struct {
+		pair MarketPair
+		id   TradeID
+	}
+
+	seen := make(map[key]bool, len(fills))
+	out := make([]Fill, 0, len(fills))
+	for _, f := range fills {
+		k := key{f.Pair, f.TradeID}
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+		out = append(out, f)
+	}
+
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].Time.Equal(out[j].Time) {
+			return out[i].TradeID < out[j].TradeID
+		}
+		return out[i].Time.Before(out[j].Time)
+	})
+
+	return out
+}
+
+// scaleAmount returns amount * num / den without intermediate overflow,
+// saturating a result beyond the range of an AmountWhole.
+func scaleAmount(amount, num, den AmountWhole) AmountWhole {
+	if den == 0 {
+		return 0
+	}
+
+	r := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(num)))
+	r.Quo(r, big.NewInt(int64(den)))
+
+	return saturateAmount(r)
+}
+
+// PnLPosition is the profit and loss of a single market, in its currency.
+type PnLPosition struct {
+	Pair MarketPair
+
+	// Volume is the volume of the instrument still held, and CostBasis what
+	// it cost including fees.
+	Volume    AmountWhole
+	CostBasis AmountWhole
+
+	// AverageCost is the cost basis per unit held.
+	AverageCost AmountWhole
+
+	// MarkPrice is the price the holding is valued at, and MarketValue its
+	// value at that price. Both are zero if no mark price was provided.
+	MarkPrice   AmountWhole
+	MarketValue AmountWhole
+
+	Realised   AmountWhole
+	Unrealised AmountWhole
+	Fees       AmountWhole
+
+	// Unmatched is the volume sold beyond what the fills and opening lots
+	// show as held. It has no cost basis, so its proceeds are left out of
+	// Realised. Add opening lots with AddLots to account for it.
+	Unmatched AmountWhole
+}
+
+// PnLReport is the profit and loss of every market with fills.
+type PnLReport struct {
+	Method     CostMethod
+	Positions  []PnLPosition
+	Realised   AmountWhole
+	Unrealised AmountWhole
+	Fees       AmountWhole
+}
+
+// PnLEngine accumulates fills and reports realised and unrealised profit and
+// loss per market. It is concurrency safe.
+type PnLEngine struct {
+	method CostMethod
+
+	mu    sync.Mutex
+	fills []Fill
+	lots  []Lot
+}
+
+// NewPnLEngine constructs a new PnLEngine using the provided cost method.
+func NewPnLEngine(method CostMethod) *PnLEngine {
+	return &PnLEngine{method: method}
+}
+
+// AddFills adds fills to the engine. Fills may be added in any order, and
+// duplicates are ignored.
+func (pe *PnLEngine) AddFills(fills ...Fill) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.fills = append(pe.fills, fills...)
+}
+
+// AddLots adds opening lots, being holdings acquired outside the fills, such as
+// an instrument deposited into the account or bought before the first fill.
+func (pe *PnLEngine) AddLots(lots ...Lot) {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	pe.lots = append(pe.lots, lots...)
+}
+
+// AddTrades adds trades from the trade history of a market.
+func (pe *PnLEngine) AddTrades(currency Currency, instrument Instrument, trades ...OrderTradeDataItem) {
+	fills := make([]Fill, len(trades))
+	for i, t := range trades {
+		fills[i] = NewFill(currency, instrument, t)
+	}
+
+	pe.AddFills(fills...)
+}
+
+// AddOrders adds the trades of orders, such as from OrderHistory.
+func (pe *PnLEngine) AddOrders(orders ...OrderDataItem) {
+	for _, o := range orders {
+		pe.AddFills(FillsFromOrder(o)...)
+	}
+}
+
+// Fills returns the fills added to the engine, without duplicates, in time
+// order.
+func (pe *PnLEngine) Fills() []Fill {
+	pe.mu.Lock()
+	defer pe.mu.Unlock()
+
+	return sortFills(pe.fills)
+}
+
+// Report computes the profit and loss of every market. Holdings are valued at
+// the mark prices provided, such as the last price of each market. Sales of
+// more than was held are reported as the position's Unmatched volume.
+func (pe *PnLEngine) Report(marks map[MarketPair]AmountWhole) (*PnLReport, error) {
+	pe.mu.Lock()
+	fills := sortFills(pe.fills)
+	opening := append([]Lot(nil), pe.lots...)
+	pe.mu.Unlock()
+
+	disposals, lots, unmatched, err := matchLots(fills, pe.method, opening)
+	if err != nil {
+		return nil, err
+	}
+
+	positions := make(map[MarketPair]*PnLPosition)
+	position := func(pair MarketPair) *PnLPosition {
+		p, ok := positions[pair]
+		if !ok {
+			p = &PnLPosition{Pair: pair}
+			positions[pair] = p
+		}
+		return p
+	}
+
+	for _, f := range fills {
+		position(f.Pair).Fees += f.Fee
+	}
+
+	for _, d := range disposals {
+		position(d.Pair).Realised += d.Gain()
+	}
+
+	for _, u := range unmatched {
+		position(u.Pair).Unmatched += u.Unmatched
+	}
+
+	for _, l := range lots {
+		p := position(l.Pair)
+		p.Volume += l.Volume
+		p.CostBasis += l.Cost
+	}
+
+	report := &PnLReport{Method: pe.method}
+	for _, p := range positions {
+		p.AverageCost = p.CostBasis.Div(p.Volume)
+
+		if mark, ok := marks[p.Pair]; ok && mark > 0 {
+			p.MarkPrice = mark
+			p.MarketValue = mark.Mul(p.Volume)
+			p.Unrealised = p.MarketValue - p.CostBasis
+		}
+
+		report.Realised += p.Realised
+		report.Unrealised += p.Unrealised
+		report.Fees += p.Fees
+		report.Positions = append(report.Positions, *p)
+	}
+
+	sort.Slice(report.Positions, func(i, j int) bool {
+		return report.Positions[i].Pair.String() < report.Positions[j].Pair.String()
+	})
+
+	return report, nil
+}
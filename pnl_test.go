@@ -0,0 +1,50 @@
+package btcmarkets
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPnLReportUnmatchedSale(t *testing.T) {
+	pair := MarketPair{Instrument: InstrumentBitcoin, Currency: CurrencyAUD}
+	day := time.Date(2017, time.July, 1, 0, 0, 0, 0, time.UTC)
+
+	pe := NewPnLEngine(CostFIFO)
+	pe.AddFills(
+		Fill{Pair: pair, Side: Bid, TradeID: 1, Time: day, Price: 100000000000, Volume: 100000000},
+		Fill{Pair: pair, Side: Ask, TradeID: 2, Time: day.Add(time.Hour), Price: 200000000000, Volume: 300000000},
+	)
+
+	report, err := pe.Report(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := report.Positions[0]
+	if p.Unmatched != 200000000 {
+		t.Errorf("Unmatched = %d, want 200000000", p.Unmatched)
+	}
+	if p.Realised != 100000000000 {
+		t.Errorf("Realised = %d, want 100000000000", p.Realised)
+	}
+
+	if _, _, err := MatchLots(pe.Fills(), CostFIFO); err == nil {
+		t.Error("MatchLots error = nil, want an error for the unmatched sale")
+	}
+
+	// Opening lots account for the volume held before the first fill.
+	pe.AddLots(Lot{Pair: pair, Acquired: day.AddDate(-1, 0, 0), Volume: 200000000, Cost: 100000000000})
+
+	report, err = pe.Report(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p = report.Positions[0]
+	if p.Unmatched != 0 || p.Volume != 0 {
+		t.Errorf("Unmatched = %d Volume = %d, want 0 and 0", p.Unmatched, p.Volume)
+	}
+	if p.Realised != 400000000000 {
+		t.Errorf("Realised = %d, want 400000000000", p.Realised)
+	}
+}
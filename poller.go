@@ -0,0 +1,68 @@
+package btcmarkets
+
+import (
+	"sync"
+	"time"
+)
+
+// poller runs a function in the background once per interval, between calls
+// to start and halt. It is the shared lifecycle of the watchers and trackers
+// which poll the API, and is concurrency safe.
+type poller struct {
+	mu   sync.Mutex
+	stop chan struct{}
+	done chan struct{}
+}
+
+// start begins calling poll in the background, first immediately when
+// immediate is true and then once per interval. poll is passed the stop
+// channel, which is closed by halt, so that it can abandon blocking sends. It
+// does nothing if the poller is already running.
+func (p *poller) start(interval time.Duration, immediate bool, poll func(stop <-chan struct{})) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.stop != nil {
+		return
+	}
+
+	p.stop = make(chan struct{})
+	p.done = make(chan struct{})
+
+	go func(stop, done chan struct{}) {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		if immediate {
+			poll(stop)
+		}
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+			}
+
+			poll(stop)
+		}
+	}(p.stop, p.done)
+}
+
+// halt stops the poller and waits for any poll in progress to finish. It does
+// nothing if the poller is not running.
+func (p *poller) halt() {
+	p.mu.Lock()
+	stop, done := p.stop, p.done
+	p.stop, p.done = nil, nil
+	p.mu.Unlock()
+
+	if stop == nil {
+		return
+	}
+
+	close(stop)
+	<-done
+}
@@ -0,0 +1,204 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"sort"
+)
+
+// PortfolioIntermediates are the currencies tried, in order, to value a
+// holding which has no market in the quote currency, such as valuing XRP in
+// AUD through XRP/BTC and BTC/AUD.
+var PortfolioIntermediates = []Currency{CurrencyBitcoin, CurrencyAUD}
+
+// PortfolioHolding is the valuation of the balance of a single currency.
+//
+// Pending funds are those reserved by open orders and withdrawals, and are
+// included in the total.
+type PortfolioHolding struct {
+	Currency  Currency
+	Total     AmountWhole
+	Pending   AmountWhole
+	Available AmountWhole
+
+	// Price is the value of one unit of the currency in the quote currency.
+	Price AmountDecimal
+
+	Value          AmountDecimal
+	PendingValue   AmountDecimal
+	AvailableValue AmountDecimal
+
+	// Allocation is the percentage of the portfolio's value in this holding.
+	Allocation float64
+
+	// Route is the markets used to price the holding, which is empty for the
+	// quote currency itself.
+	Route []MarketPair
+}
+
+// Portfolio is the valuation of every balance in the account in a single
+// quote currency, with holdings ordered from largest to smallest value.
+type Portfolio struct {
+	Quote          Currency
+	Holdings       []PortfolioHolding
+	Value          AmountDecimal
+	PendingValue   AmountDecimal
+	AvailableValue AmountDecimal
+}
+
+// Holding returns the holding of the provided currency.
+func (p *Portfolio) Holding(currency Currency) (PortfolioHolding, bool) {
+	for _, h := range p.Holdings {
+		if h.Currency == currency {
+			return h, true
+		}
+	}
+
+	return PortfolioHolding{}, false
+}
+
+// Portfolio values every balance in the account in the quote currency, using
+// the last price of each market from MarketTick. Currencies without a market
+// in the quote currency are valued through PortfolioIntermediates. Each market
+// is only requested once.
+func (c *Client) Portfolio(quote Currency) (*Portfolio, error) {
+	bal, err := c.AccountBalance()
+	if err != nil {
+		return nil, err
+	}
+
+	pr := &portfolioPricer{c: c, ticks: make(map[MarketPair]*MarketTickResponse)}
+	p := &Portfolio{Quote: quote}
+
+	for _, b := range *bal {
+		if b.Balance == 0 && b.Pending == 0 {
+			continue
+		}
+
+		price, route, err := pr.price(b.Currency, quote)
+		if err != nil {
+			return nil, err
+		}
+
+		h := PortfolioHolding{
+			Currency:  b.Currency,
+			Total:     b.Balance,
+			Pending:   b.Pending,
+			Available: b.Balance - b.Pending,
+			Price:     price,
+			Route:     route,
+		}
+		h.Value = h.Total.ToAmountDecimal() * price
+		h.PendingValue = h.Pending.ToAmountDecimal() * price
+		h.AvailableValue = h.Available.ToAmountDecimal() * price
+
+		p.Value += h.Value
+		p.PendingValue += h.PendingValue
+		p.AvailableValue += h.AvailableValue
+		p.Holdings = append(p.Holdings, h)
+	}
+
+	for i := range p.Holdings {
+		if p.Value != 0 {
+			p.Holdings[i].Allocation = float64(p.Holdings[i].Value/p.Value) * 100
+		}
+	}
+
+	sort.SliceStable(p.Holdings, func(i, j int) bool { return p.Holdings[i].Value > p.Holdings[j].Value })
+
+	return p, nil
+}
+
+// portfolioPricer prices currencies from market ticks, caching each tick
+// (including markets which do not exist) for the life of a valuation.
+type portfolioPricer struct {
+	c     *Client
+	ticks map[MarketPair]*MarketTickResponse
+}
+
+// price returns the value of one unit of "from" in "to", along with the
+// markets used.
+func (pr *portfolioPricer) price(from, to Currency) (AmountDecimal, []MarketPair, error) {
+	if from == to {
+		return 1, nil, nil
+	}
+
+	price, pair, ok, err := pr.direct(from, to)
+	if err != nil {
+		return 0, nil, err
+	}
+	if ok {
+		return price, []MarketPair{pair}, nil
+	}
+
+	for _, via := range PortfolioIntermediates {
+		if via == from || via == to {
+			continue
+		}
+
+		first, firstPair, ok, err := pr.direct(from, via)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		second, secondPair, ok, err := pr.direct(via, to)
+		if err != nil {
+			return 0, nil, err
+		}
+		if !ok {
+			continue
+		}
+
+		return first * second, []MarketPair{firstPair, secondPair}, nil
+	}
+
+	return 0, nil, fmt.Errorf("No market available to value %s in %s", from, to)
+}
+
+// direct prices "from" in "to" from a single market, in either direction.
+func (pr *portfolioPricer) direct(from, to Currency) (AmountDecimal, MarketPair, bool, error) {
+	pair := MarketPair{Instrument: Instrument(from), Currency: to}
+	tick, err := pr.tick(pair)
+	if err != nil {
+		return 0, MarketPair{}, false, err
+	}
+	if tick != nil {
+		return tick.Last, pair, true, nil
+	}
+
+	pair = MarketPair{Instrument: Instrument(to), Currency: from}
+	tick, err = pr.tick(pair)
+	if err != nil {
+		return 0, MarketPair{}, false, err
+	}
+	if tick != nil {
+		return 1 / tick.Last, pair, true, nil
+	}
+
+	return 0, MarketPair{}, false, nil
+}
+
+// tick returns the tick of a market, or nil if the market has no last price.
+// The API answers a market which does not exist with an error body, which
+// decodes to a tick without a price, and only that is cached as absent.
+// Request failures are returned, so that a transient error cannot send the
+// valuation through a different route.
+func (pr *portfolioPricer) tick(pair MarketPair) (*MarketTickResponse, error) {
+	if tick, ok := pr.ticks[pair]; ok {
+		return tick, nil
+	}
+
+	tick, err := pr.c.MarketTick(pair.Instrument, pair.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to price %s (%s)", pair, err.Error())
+	}
+
+	if tick.Last <= 0 {
+		tick = nil
+	}
+	pr.ticks[pair] = tick
+
+	return tick, nil
+}
@@ -0,0 +1,154 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"time"
+)
+
+// replaceCancelAttempts is the number of times ReplaceOrder checks an order
+// after cancelling it, waiting for the cancellation to take effect.
+const replaceCancelAttempts = 5
+
+// ReplaceStep is a step of ReplaceOrder.
+type ReplaceStep string
+
+// Enumerated replace steps, in the order they are performed.
+const (
+	ReplaceStepLookup  ReplaceStep = "Lookup"
+	ReplaceStepCancel  ReplaceStep = "Cancel"
+	ReplaceStepConfirm ReplaceStep = "Confirm"
+	ReplaceStepPlace   ReplaceStep = "Place"
+	ReplaceStepDone    ReplaceStep = "Done"
+)
+
+// ReplaceResult describes what happened at each step of ReplaceOrder.
+type ReplaceResult struct {
+	// Step is the last step reached. It is ReplaceStepDone on success, or the
+	// step which failed.
+	Step ReplaceStep
+
+	// Original is the order before it was cancelled.
+	Original OrderDataItem
+
+	// Cancelled is the order after the cancellation took effect.
+	Cancelled OrderDataItem
+
+	// FilledDuringCancel is the volume matched between looking the order up
+	// and the cancellation taking effect.
+	FilledDuringCancel AmountWhole
+
+	// Replacement is the response from placing the new order, which is nil if
+	// nothing was left to place.
+	Replacement *OrderCreateResponse
+
+	// ReplacementVolume is the volume of the new order.
+	ReplacementVolume AmountWhole
+}
+
+// ReplaceError is returned by ReplaceOrder when a step fails.
+type ReplaceError struct {
+	Step ReplaceStep
+	Err  error
+}
+
+func (e *ReplaceError) Error() string {
+	return fmt.Sprintf("Replace order failed at %s step (%s)", e.Step, e.Err.Error())
+}
+
+// ReplaceOrder amends an open order by cancelling it and placing a new limit
+// order at the provided price. The new order is for the volume left open once
+// the cancellation has taken effect, so fills which happen in between are not
+// repeated. If volume is greater than zero it is used instead, unless the
+// cancelled order has less left open.
+//
+// The result is always returned, describing what happened at each step. If the
+// order was fully matched before it could be cancelled no new order is placed.
+func (c *Client) ReplaceOrder(id OrderID, price, volume AmountWhole) (*ReplaceResult, error) {
+	res := &ReplaceResult{Step: ReplaceStepLookup}
+
+	original, err := c.orderDetail(id)
+	if err != nil {
+		return res, &ReplaceError{Step: res.Step, Err: err}
+	}
+	res.Original = original
+
+	if !original.Status.IsOpen() {
+		return res, &ReplaceError{Step: res.Step, Err: fmt.Errorf("Order %d is %s", id, original.Status)}
+	}
+
+	res.Step = ReplaceStepCancel
+	ocr, err := c.OrderCancel(id)
+	if err == nil && !ocr.Success {
+		err = fmt.Errorf("%d: %s", ocr.ErrorCode, ocr.ErrorMessage)
+	}
+	if err == nil && len(ocr.Responses) > 0 && !ocr.Responses[0].Success {
+		err = fmt.Errorf("%d: %s", ocr.Responses[0].ErrorCode, ocr.Responses[0].ErrorMessage)
+	}
+	if err != nil {
+		return res, &ReplaceError{Step: res.Step, Err: err}
+	}
+
+	res.Step = ReplaceStepConfirm
+	var cancelled OrderDataItem
+	for attempt := 0; ; attempt++ {
+		cancelled, err = c.orderDetail(id)
+		if err != nil {
+			return res, &ReplaceError{Step: res.Step, Err: err}
+		}
+
+		if cancelled.Status.IsTerminal() {
+			break
+		}
+
+		if attempt == replaceCancelAttempts {
+			return res, &ReplaceError{Step: res.Step, Err: fmt.Errorf("Order %d is still %s after cancelling", id, cancelled.Status)}
+		}
+
+		time.Sleep(executionPollInterval)
+	}
+	res.Cancelled = cancelled
+	res.FilledDuringCancel = tradedVolume(cancelled) - tradedVolume(original)
+
+	res.Step = ReplaceStepPlace
+	res.ReplacementVolume = cancelled.VolumeOpen
+	if volume > 0 && volume < res.ReplacementVolume {
+		res.ReplacementVolume = volume
+	}
+
+	if res.ReplacementVolume <= 0 {
+		res.Step = ReplaceStepDone
+		return res, nil
+	}
+
+	res.Replacement, err = c.OrderCreate(original.Currency, original.Instrument, price, res.ReplacementVolume, original.OrderSide, Limit, "")
+	if err == nil && !res.Replacement.Success {
+		err = fmt.Errorf("%d: %s", res.Replacement.ErrorCode, res.Replacement.ErrorMessage)
+	}
+	if err != nil {
+		return res, &ReplaceError{Step: res.Step, Err: err}
+	}
+
+	res.Step = ReplaceStepDone
+
+	return res, nil
+}
+
+// orderDetail fetches the details of a single order.
+func (c *Client) orderDetail(id OrderID) (OrderDataItem, error) {
+	odr, err := c.OrderDetail(id)
+	if err != nil {
+		return OrderDataItem{}, err
+	}
+
+	if !odr.Success {
+		return OrderDataItem{}, fmt.Errorf("Failed to fetch order %d (%s: %s)", id, odr.ErrorCode, odr.ErrorMessage)
+	}
+
+	for _, o := range odr.Orders {
+		if o.OrderID == id {
+			return o, nil
+		}
+	}
+
+	return OrderDataItem{}, fmt.Errorf("Order %d not found", id)
+}
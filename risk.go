@@ -0,0 +1,387 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// DefaultRiskTickMaxAge is the default age after which a RiskManager fetches a
+// fresh tick for its price band and notional checks.
+const DefaultRiskTickMaxAge = 10 * time.Second
+
+// RiskCheck names a pre-trade risk check.
+type RiskCheck string
+
+// Enumerated risk checks.
+const (
+	RiskCheckKillSwitch RiskCheck = "KillSwitch"
+	RiskCheckOrder      RiskCheck = "Order"
+	RiskCheckNotional   RiskCheck = "Notional"
+	RiskCheckPosition   RiskCheck = "Position"
+	RiskCheckPriceBand  RiskCheck = "PriceBand"
+	RiskCheckDailyLoss  RiskCheck = "DailyLoss"
+)
+
+// RiskError is returned when an order is rejected by a risk check, before it
+// is sent to the API.
+type RiskError struct {
+	Check  RiskCheck
+	Reason string
+}
+
+func (e *RiskError) Error() string {
+	return fmt.Sprintf("Order rejected by %s risk check (%s)", e.Check, e.Reason)
+}
+
+// RiskLimits configures the checks made by a RiskManager. Zero values disable
+// the corresponding check.
+type RiskLimits struct {
+	// MaxOrderNotional is the largest value (price multiplied by volume) of a
+	// single order, in the order's currency.
+	MaxOrderNotional AmountWhole
+
+	// MaxPosition is the largest holding of each instrument which bids may
+	// build up to, counting the volume of bids which are still open.
+	MaxPosition map[Instrument]AmountWhole
+
+	// PriceBand is the largest fraction (such as 0.05 for 5%) by which a limit
+	// price may differ from the last price, protecting against fat fingers.
+	PriceBand float64
+
+	// DailyLossLimit is the largest loss which may be recorded with RecordPnL
+	// in a day before all orders are rejected.
+	DailyLossLimit AmountWhole
+}
+
+// riskTick is a tick cached by a RiskManager.
+type riskTick struct {
+	tick     *MarketTickResponse
+	received time.Time
+}
+
+// RiskManager wraps order placement with pre-trade risk checks. Orders placed
+// through its OrderCreate are checked against its limits, and rejected with a
+// *RiskError before the request is signed and sent. It is concurrency safe.
+type RiskManager struct {
+	// TickMaxAge is the age after which a fresh tick is fetched for the price
+	// band and notional checks.
+	TickMaxAge time.Duration
+
+	c      *Client
+	limits RiskLimits
+
+	mu        sync.Mutex
+	killed    string
+	positions map[Instrument]AmountWhole
+	reserved  map[Instrument]AmountWhole
+	lossDay   string
+	loss      AmountWhole
+	ticks     map[MarketPair]riskTick
+}
+
+// NewRiskManager constructs a new RiskManager enforcing the provided limits.
+func NewRiskManager(c *Client, limits RiskLimits) *RiskManager {
+	return &RiskManager{
+		TickMaxAge: DefaultRiskTickMaxAge,
+		c:          c,
+		limits:     limits,
+		positions:  make(map[Instrument]AmountWhole),
+		reserved:   make(map[Instrument]AmountWhole),
+		ticks:      make(map[MarketPair]riskTick),
+	}
+}
+
+// Kill engages the kill switch, rejecting every order until Resume is called.
+func (rm *RiskManager) Kill(reason string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if reason == "" {
+		reason = "Kill switch engaged"
+	}
+	rm.killed = reason
+}
+
+// Resume releases the kill switch.
+func (rm *RiskManager) Resume() {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.killed = ""
+}
+
+// Killed reports whether the kill switch is engaged.
+func (rm *RiskManager) Killed() bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.killed != ""
+}
+
+// SetPosition sets the current holding of an instrument, such as from the
+// account balance at startup.
+func (rm *RiskManager) SetPosition(instrument Instrument, volume AmountWhole) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.positions[instrument] = volume
+}
+
+// Position returns the current holding of an instrument.
+func (rm *RiskManager) Position(instrument Instrument) AmountWhole {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.positions[instrument]
+}
+
+// Reserved returns the volume of an instrument reserved by bids which passed
+// Check and have not yet been filled or released.
+func (rm *RiskManager) Reserved(instrument Instrument) AmountWhole {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	return rm.reserved[instrument]
+}
+
+// RecordFill updates the holding of an instrument with a fill. A bid's fill
+// moves its volume from the reservation to the holding.
+func (rm *RiskManager) RecordFill(instrument Instrument, side OrderSide, volume AmountWhole) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if side == Bid {
+		rm.positions[instrument] += volume
+		rm.release(instrument, volume)
+	} else {
+		rm.positions[instrument] -= volume
+	}
+}
+
+// Release releases volume reserved by Check which will not be filled, such as
+// the open volume of a cancelled bid. OrderCreate releases the volume of
+// orders which could not be placed.
+func (rm *RiskManager) Release(instrument Instrument, volume AmountWhole) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.release(instrument, volume)
+}
+
+// release reduces the volume reserved of an instrument, no further than zero.
+// It must be called with the lock held.
+func (rm *RiskManager) release(instrument Instrument, volume AmountWhole) {
+	if volume >= rm.reserved[instrument] {
+		delete(rm.reserved, instrument)
+		return
+	}
+
+	rm.reserved[instrument] -= volume
+}
+
+// RecordPnL adds realised profit (or loss, when negative) to today's total.
+func (rm *RiskManager) RecordPnL(amount AmountWhole) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.rollDay()
+	rm.loss -= amount
+}
+
+// DailyLoss returns the loss recorded today.
+func (rm *RiskManager) DailyLoss() AmountWhole {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.rollDay()
+	return rm.loss
+}
+
+// UpdateTick provides a tick for the price band and notional checks, such as
+// from a TickerWatcher, saving a request when an order is checked.
+func (rm *RiskManager) UpdateTick(tick *MarketTickResponse) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	rm.ticks[MarketPair{Instrument: tick.Instrument, Currency: tick.Currency}] = riskTick{tick: tick, received: time.Now()}
+}
+
+// Check runs every risk check against an order, returning a *RiskError if it
+// is rejected. The volume of a bid which passes is reserved against
+// MaxPosition until it is filled (see RecordFill) or released (see Release).
+func (rm *RiskManager) Check(req OrderCreateRequest) error {
+	rm.mu.Lock()
+	killed := rm.killed
+	rm.rollDay()
+	loss := rm.loss
+	rm.mu.Unlock()
+
+	if killed != "" {
+		return &RiskError{Check: RiskCheckKillSwitch, Reason: killed}
+	}
+
+	if rm.limits.DailyLossLimit > 0 && loss >= rm.limits.DailyLossLimit {
+		return &RiskError{
+			Check:  RiskCheckDailyLoss,
+			Reason: fmt.Sprintf("Loss today of %f has reached the limit of %f", loss.ToAmountDecimal(), rm.limits.DailyLossLimit.ToAmountDecimal()),
+		}
+	}
+
+	if req.OrderSide != Ask && req.OrderSide != Bid {
+		return &RiskError{Check: RiskCheckOrder, Reason: fmt.Sprintf("Unknown order side %q", req.OrderSide)}
+	}
+
+	if req.OrderType != Limit && req.OrderType != Market {
+		return &RiskError{Check: RiskCheckOrder, Reason: fmt.Sprintf("Unknown order type %q", req.OrderType)}
+	}
+
+	if req.Volume <= 0 {
+		return &RiskError{Check: RiskCheckOrder, Reason: "Volume must be greater than zero"}
+	}
+
+	if err := rm.checkPrice(req); err != nil {
+		return err
+	}
+
+	return rm.reserve(req)
+}
+
+// reserve checks that a bid will not take the position over its limit, and
+// reserves its volume if it does not.
+func (rm *RiskManager) reserve(req OrderCreateRequest) error {
+	if req.OrderSide != Bid {
+		return nil
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	position := rm.positions[req.Instrument] + rm.reserved[req.Instrument] + req.Volume
+	if limit, ok := rm.limits.MaxPosition[req.Instrument]; ok && position > limit {
+		return &RiskError{
+			Check:  RiskCheckPosition,
+			Reason: fmt.Sprintf("Position of %f %s including open bids would exceed the limit of %f", position.ToAmountDecimal(), req.Instrument, limit.ToAmountDecimal()),
+		}
+	}
+
+	rm.reserved[req.Instrument] += req.Volume
+
+	return nil
+}
+
+// checkPrice runs the price band and notional checks.
+func (rm *RiskManager) checkPrice(req OrderCreateRequest) error {
+	if rm.limits.PriceBand <= 0 && (rm.limits.MaxOrderNotional <= 0 || req.OrderType == Limit) {
+		return rm.checkNotional(req, req.Price)
+	}
+
+	tick, err := rm.tick(MarketPair{Instrument: req.Instrument, Currency: req.Currency})
+	if err != nil {
+		return err
+	}
+
+	last := tick.Last.ToAmountWhole()
+	if last <= 0 {
+		return &RiskError{Check: RiskCheckPriceBand, Reason: fmt.Sprintf("No last price for %s/%s", req.Instrument, req.Currency)}
+	}
+
+	if req.OrderType == Market {
+		return rm.checkNotional(req, last)
+	}
+
+	if rm.limits.PriceBand > 0 {
+		deviation := math.Abs(float64(req.Price-last)) / float64(last)
+		if deviation > rm.limits.PriceBand {
+			return &RiskError{
+				Check:  RiskCheckPriceBand,
+				Reason: fmt.Sprintf("Price %f is %.2f%% from the last price of %f", req.Price.ToAmountDecimal(), deviation*100, last.ToAmountDecimal()),
+			}
+		}
+	}
+
+	return rm.checkNotional(req, req.Price)
+}
+
+// checkNotional checks the value of the order at the provided price.
+func (rm *RiskManager) checkNotional(req OrderCreateRequest, price AmountWhole) error {
+	if rm.limits.MaxOrderNotional <= 0 {
+		return nil
+	}
+
+	notional := price.Mul(req.Volume)
+	if notional > rm.limits.MaxOrderNotional {
+		return &RiskError{
+			Check:  RiskCheckNotional,
+			Reason: fmt.Sprintf("Order value of %f %s exceeds the limit of %f", notional.ToAmountDecimal(), req.Currency, rm.limits.MaxOrderNotional.ToAmountDecimal()),
+		}
+	}
+
+	return nil
+}
+
+// OrderCreate checks an order against the risk limits, then places it with the
+// client's OrderCreate. It takes the same arguments as Client.OrderCreate. The
+// reservation of a bid which the API refuses is released.
+func (rm *RiskManager) OrderCreate(
+	currency Currency,
+	instrument Instrument,
+	price AmountWhole,
+	volume AmountWhole,
+	side OrderSide,
+	ordertype OrderType,
+	requestID string,
+) (*OrderCreateResponse, error) {
+	err := rm.Check(OrderCreateRequest{
+		Currency:        currency,
+		Instrument:      instrument,
+		Price:           price,
+		Volume:          volume,
+		OrderSide:       side,
+		OrderType:       ordertype,
+		ClientRequestID: requestID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// An error leaves the volume reserved, as the order may have been placed.
+	ocr, err := rm.c.OrderCreate(currency, instrument, price, volume, side, ordertype, requestID)
+	if side == Bid && err == nil && !ocr.Success {
+		rm.Release(instrument, volume)
+	}
+
+	return ocr, err
+}
+
+// tick returns a cached tick for the market, fetching a new one if it is
+// missing or older than TickMaxAge.
+func (rm *RiskManager) tick(pair MarketPair) (*MarketTickResponse, error) {
+	rm.mu.Lock()
+	cached, ok := rm.ticks[pair]
+	rm.mu.Unlock()
+
+	if ok && time.Since(cached.received) <= rm.TickMaxAge {
+		return cached.tick, nil
+	}
+
+	tick, err := rm.c.MarketTick(pair.Instrument, pair.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	rm.UpdateTick(tick)
+
+	return tick, nil
+}
+
+// rollDay resets the daily loss when the day changes. It must be called with
+// the lock held.
+func (rm *RiskManager) rollDay() {
+	today := time.Now().Format("2006-01-02")
+	if rm.lossDay != today {
+		rm.lossDay = today
+		rm.loss = 0
+	}
+}
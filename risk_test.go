@@ -0,0 +1,38 @@
+package btcmarkets
+
+import "testing"
+
+func TestRiskManagerReservesOpenBids(t *testing.T) {
+	rm := NewRiskManager(nil, RiskLimits{MaxPosition: map[Instrument]AmountWhole{InstrumentBitcoin: 300000000}})
+	rm.SetPosition(InstrumentBitcoin, 100000000)
+
+	bid := OrderCreateRequest{Currency: CurrencyAUD, Instrument: InstrumentBitcoin, Price: 1000000000, Volume: 100000000, OrderSide: Bid, OrderType: Limit}
+
+	for i := 0; i < 2; i++ {
+		if err := rm.Check(bid); err != nil {
+			t.Fatalf("bid %d: %v", i, err)
+		}
+	}
+
+	// Two open bids take the position to its limit.
+	if err := rm.Check(bid); err == nil {
+		t.Fatal("Check error = nil, want a position rejection")
+	}
+	if got := rm.Reserved(InstrumentBitcoin); got != 200000000 {
+		t.Errorf("Reserved() = %d, want 200000000", got)
+	}
+
+	// Filling one bid and cancelling the other frees room for one more.
+	rm.RecordFill(InstrumentBitcoin, Bid, 100000000)
+	rm.Release(InstrumentBitcoin, 100000000)
+	if got := rm.Reserved(InstrumentBitcoin); got != 0 {
+		t.Errorf("Reserved() = %d, want 0", got)
+	}
+
+	if err := rm.Check(bid); err != nil {
+		t.Fatal(err)
+	}
+	if err := rm.Check(bid); err == nil {
+		t.Fatal("Check error = nil, want a position rejection")
+	}
+}
@@ -1,6 +1,15 @@
 package btcmarkets
 
-import "math"
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// amountScale is the number of AmountWhole units in a single AmountDecimal unit.
+const amountScale = 100000000
 
 // AmountDecimal is a float type which represents the API numbers returned which
 // can have decimal places.
@@ -30,6 +39,81 @@ func (amount AmountWhole) ToAmountDecimal() AmountDecimal {
 	return AmountDecimal(amount) / AmountDecimal(100000000)
 }
 
+// DecimalString returns the amount as an exact decimal string, such as
+// "4502.25" or "-0.00000001", without the rounding of a float conversion.
+func (amount AmountWhole) DecimalString() string {
+	sign := ""
+	whole := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		whole = uint64(-amount)
+	}
+
+	s := fmt.Sprintf("%s%d.%08d", sign, whole/amountScale, whole%amountScale)
+	s = strings.TrimRight(s, "0")
+
+	return strings.TrimSuffix(s, ".")
+}
+
+// Mul multiplies two AmountWhole values (such as a price and a volume) and
+// returns the result as an AmountWhole, without the loss of precision or
+// intermediate overflow that multiplying the raw integers would cause. A
+// result beyond the range of an AmountWhole saturates at its largest or
+// smallest value.
+func (amount AmountWhole) Mul(other AmountWhole) AmountWhole {
+	r := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(int64(other)))
+	r.Quo(r, big.NewInt(amountScale))
+
+	return saturateAmount(r)
+}
+
+// Div divides one AmountWhole value by another (such as a notional by a
+// volume to give a price) and returns the result as an AmountWhole, rounded
+// towards zero. Dividing by zero returns zero, and a result beyond the range of
+// an AmountWhole saturates at its largest or smallest value.
+func (amount AmountWhole) Div(other AmountWhole) AmountWhole {
+	if other == 0 {
+		return 0
+	}
+
+	r := new(big.Int).Mul(big.NewInt(int64(amount)), big.NewInt(amountScale))
+	r.Quo(r, big.NewInt(int64(other)))
+
+	return saturateAmount(r)
+}
+
+// saturateAmount converts an integer to an AmountWhole, clamping it to the
+// range of an AmountWhole.
+func saturateAmount(r *big.Int) AmountWhole {
+	if !r.IsInt64() {
+		if r.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+
+	return AmountWhole(r.Int64())
+}
+
+// ParseAmountWhole converts a decimal string as returned by the API (for
+// example "4502.25" or "1e-05") into an AmountWhole exactly, rounding to the
+// nearest unit if more than 8 decimal places are present.
+func ParseAmountWhole(s string) (AmountWhole, error) {
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("Invalid decimal amount %q", s)
+	}
+
+	r.Mul(r, new(big.Rat).SetInt64(amountScale))
+
+	whole, err := strconv.ParseInt(r.FloatString(0), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("Decimal amount %q out of range", s)
+	}
+
+	return AmountWhole(whole), nil
+}
+
 // Currency represents the name of a real-world or crypto currency
 type Currency string
 
@@ -56,3 +140,15 @@ const (
 	InstrumentLitecoin   Instrument = "LTC"
 	InstrumentRipple     Instrument = "XRP"
 )
+
+// MarketPair identifies a market by its instrument and currency, such as
+// BTC/AUD.
+type MarketPair struct {
+	Instrument Instrument `json:"instrument"`
+	Currency   Currency   `json:"currency"`
+}
+
+// String is present to implement the Stringer interface for the MarketPair type.
+func (mp MarketPair) String() string {
+	return fmt.Sprintf("%s/%s", mp.Instrument, mp.Currency)
+}
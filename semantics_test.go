@@ -0,0 +1,26 @@
+package btcmarkets
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAmountWholeMulDiv(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		got  AmountWhole
+		want AmountWhole
+	}{
+		{"price times volume", AmountWhole(450225000000).Mul(150000000), 675337500000},
+		{"notional over volume", AmountWhole(675337500000).Div(150000000), 450225000000},
+		{"divide by zero", AmountWhole(100000000).Div(0), 0},
+		{"Mul overflow", AmountWhole(math.MaxInt64).Mul(1000000000), math.MaxInt64},
+		{"Mul negative overflow", AmountWhole(math.MinInt64).Mul(1000000000), math.MinInt64},
+		{"Div overflow", AmountWhole(math.MaxInt64).Div(1), math.MaxInt64},
+		{"scaleAmount overflow", scaleAmount(math.MaxInt64, 3, 2), math.MaxInt64},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %d, want %d", tc.name, tc.got, tc.want)
+		}
+	}
+}
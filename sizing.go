@@ -0,0 +1,187 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+)
+
+// OrderSize is an order sized from an amount of its currency by SizeOrder.
+type OrderSize struct {
+	Currency   Currency
+	Instrument Instrument
+	Side       OrderSide
+	Price      AmountWhole
+	Volume     AmountWhole
+
+	// Notional is the value of the order (price multiplied by volume).
+	Notional AmountWhole
+
+	// FeeRate is the trading fee rate applied to the notional.
+	FeeRate AmountWhole
+
+	// Fee is the expected trading fee, in the order's currency.
+	Fee AmountWhole
+
+	// Total is the amount spent including the fee for a bid, or the amount
+	// received after the fee for an ask.
+	Total AmountWhole
+}
+
+// VolumeRule is the volume step and minimum order volume of an instrument.
+type VolumeRule struct {
+	// Step is the increment order volumes must be a multiple of. Zero allows
+	// any volume of 8 decimal places.
+	Step AmountWhole
+
+	// Minimum is the smallest volume of an order. Zero has no minimum.
+	Minimum AmountWhole
+}
+
+// VolumeRules are the volume rules of each instrument, applied by SizeOrder.
+// The exchange does not publish them through the API, so it is empty by
+// default and should be filled in with the rules of the instruments traded. It
+// must not be modified while orders are being sized.
+var VolumeRules = map[Instrument]VolumeRule{}
+
+// Round rounds a volume to a multiple of the rule's step, up or down.
+func (r VolumeRule) Round(volume AmountWhole, up bool) AmountWhole {
+	if r.Step <= 0 {
+		return volume
+	}
+
+	rounded := volume / r.Step * r.Step
+	if up && rounded < volume {
+		rounded += r.Step
+	}
+
+	return rounded
+}
+
+// SizeOrder works out the volume of an order from an amount of its currency,
+// such as "spend 500 AUD on BTC". For a bid the amount is the most to spend
+// including the trading fee, and for an ask it is the least to receive after
+// the trading fee. The fee rate comes from AccountTradingFee.
+//
+// The volume is rounded to the instrument's step in VolumeRules, or to the 8
+// decimal places of an AmountWhole when it has none, down for a bid and up for
+// an ask. An amount which sizes below the instrument's minimum volume is an
+// error.
+//
+// If price is zero the best price from MarketTick is used. AUD prices are
+// rounded to whole cents, down for a bid and up for an ask. The account balance
+// is checked to ensure the order can be afforded.
+func (c *Client) SizeOrder(currency Currency, instrument Instrument, side OrderSide, amount, price AmountWhole) (*OrderSize, error) {
+	if side != Ask && side != Bid {
+		return nil, fmt.Errorf("Unknown order side %q", side)
+	}
+
+	if amount <= 0 {
+		return nil, errors.New("Amount must be greater than zero")
+	}
+
+	if price == 0 {
+		tick, err := c.MarketTick(instrument, currency)
+		if err != nil {
+			return nil, err
+		}
+
+		price = tick.Ask.ToAmountWhole()
+		if side == Ask {
+			price = tick.Bid.ToAmountWhole()
+		}
+
+		if price <= 0 {
+			return nil, fmt.Errorf("No price available for %s/%s", instrument, currency)
+		}
+	}
+
+	if currency == CurrencyAUD {
+		price = roundAUDPrice(price, side)
+	}
+
+	fee, err := c.AccountTradingFee(instrument, currency)
+	if err != nil {
+		return nil, err
+	}
+	if !fee.Success {
+		return nil, fmt.Errorf("Failed to fetch trading fee (%d: %s)", fee.ErrorCode, fee.ErrorMessage)
+	}
+
+	size := &OrderSize{
+		Currency:   currency,
+		Instrument: instrument,
+		Side:       side,
+		Price:      price,
+		FeeRate:    fee.TradingFee,
+	}
+
+	rule := VolumeRules[instrument]
+	if side == Bid {
+		notional := amount.Div(amountScale + size.FeeRate)
+		size.Volume = rule.Round(notional.Div(price), false)
+	} else {
+		notional := amount.Div(amountScale - size.FeeRate)
+		size.Volume = notional.Div(price)
+		// Round up so that at least the amount is received
+		if price.Mul(size.Volume) < notional {
+			size.Volume++
+		}
+		size.Volume = rule.Round(size.Volume, true)
+	}
+
+	if size.Volume <= 0 {
+		return nil, fmt.Errorf("Amount is too small to buy or sell any %s", instrument)
+	}
+
+	if size.Volume < rule.Minimum {
+		return nil, fmt.Errorf("Volume of %f %s is below the minimum order of %f", size.Volume.ToAmountDecimal(), instrument, rule.Minimum.ToAmountDecimal())
+	}
+
+	size.Notional = price.Mul(size.Volume)
+	size.Fee = size.Notional.Mul(size.FeeRate)
+	if side == Bid {
+		size.Total = size.Notional + size.Fee
+	} else {
+		size.Total = size.Notional - size.Fee
+	}
+
+	bal, err := c.AccountBalance()
+	if err != nil {
+		return nil, err
+	}
+
+	need, from := size.Total, currency
+	if side == Ask {
+		need, from = size.Volume, Currency(instrument)
+	}
+
+	for _, b := range *bal {
+		if b.Currency != from {
+			continue
+		}
+
+		if available := b.Balance - b.Pending; available < need {
+			return size, fmt.Errorf("Insufficient %s balance, %f is available and %f is required", from, available.ToAmountDecimal(), need.ToAmountDecimal())
+		}
+
+		return size, nil
+	}
+
+	return size, fmt.Errorf("No %s balance", from)
+}
+
+// OrderCreateQuote sizes an order with SizeOrder and places it as a limit
+// order, returning the size along with the response.
+func (c *Client) OrderCreateQuote(currency Currency, instrument Instrument, side OrderSide, amount, price AmountWhole, requestID string) (*OrderSize, *OrderCreateResponse, error) {
+	size, err := c.SizeOrder(currency, instrument, side, amount, price)
+	if err != nil {
+		return size, nil, err
+	}
+
+	ocr, err := c.OrderCreate(currency, instrument, size.Price, size.Volume, side, Limit, requestID)
+	if err != nil {
+		return size, nil, err
+	}
+
+	return size, ocr, nil
+}
@@ -0,0 +1,54 @@
+package btcmarkets
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestSizeOrderVolumeRule(t *testing.T) {
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/account/BTC/AUD/tradingfee":
+			writeJSON(t, w, AccountTradingFeeResponse{Success: true})
+		case "/account/balance":
+			writeJSON(t, w, AccountBalanceResponse{
+				{Currency: CurrencyAUD, Balance: 100000000000},
+				{Currency: Currency(InstrumentBitcoin), Balance: 100000000},
+			})
+		default:
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+	})
+
+	saved, had := VolumeRules[InstrumentBitcoin]
+	VolumeRules[InstrumentBitcoin] = VolumeRule{Step: 100000, Minimum: 1000000}
+	t.Cleanup(func() {
+		if had {
+			VolumeRules[InstrumentBitcoin] = saved
+		} else {
+			delete(VolumeRules, InstrumentBitcoin)
+		}
+	})
+
+	// 100 AUD at 3000 AUD is 0.03333333 BTC, rounded to steps of 0.001.
+	for _, tc := range []struct {
+		side OrderSide
+		want AmountWhole
+	}{
+		{Bid, 3300000},
+		{Ask, 3400000},
+	} {
+		size, err := c.SizeOrder(CurrencyAUD, InstrumentBitcoin, tc.side, 10000000000, 300000000000)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.side, err)
+		}
+		if size.Volume != tc.want {
+			t.Errorf("%s volume = %d, want %d", tc.side, size.Volume, tc.want)
+		}
+	}
+
+	// 20 AUD is 0.00666666 BTC, below the minimum of 0.01.
+	if _, err := c.SizeOrder(CurrencyAUD, InstrumentBitcoin, Bid, 2000000000, 300000000000); err == nil {
+		t.Error("SizeOrder error = nil, want an error below the minimum volume")
+	}
+}
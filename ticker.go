@@ -0,0 +1,190 @@
+package btcmarkets
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// tickerPairInterval is the minimum time between polls of a single pair,
+	// which keeps a TickerWatcher within half of the client's 10x / 10sec rate
+	// limit and leaves the rest for other calls.
+	tickerPairInterval = 2 * time.Second
+
+	// tickerSubscriberBuffer is the number of events buffered per subscriber.
+	tickerSubscriberBuffer = 16
+
+	// DefaultTickerStaleAfter is the default duration after which a feed whose
+	// timestamp has not advanced is reported as stale.
+	DefaultTickerStaleAfter = 2 * time.Minute
+)
+
+// TickerEvent is sent to TickerWatcher subscribers when the tick of a market
+// changes, when its feed becomes stale or recovers, or when polling it fails.
+type TickerEvent struct {
+	Pair  MarketPair
+	Tick  *MarketTickResponse
+	Stale bool
+	Err   error
+}
+
+// tickerState is the last known state of a market watched by a TickerWatcher.
+type tickerState struct {
+	tick     *MarketTickResponse
+	advanced time.Time
+	stale    bool
+}
+
+// TickerWatcher polls the ticks of multiple markets and fans out changes to
+// any number of subscribers. An event is only sent when the bid, ask or last
+// price of a market changes, when a market's feed has not advanced its
+// timestamp for StaleAfter, and when a stale feed advances again. It is
+// concurrency safe.
+type TickerWatcher struct {
+	// StaleAfter is the duration without the tick timestamp advancing after
+	// which the feed is reported as stale. It must be set before Start.
+	StaleAfter time.Duration
+
+	c        *Client
+	pairs    []MarketPair
+	interval time.Duration
+
+	mu     sync.Mutex
+	subs   map[int]chan TickerEvent
+	nextID int
+	state  map[MarketPair]*tickerState
+	next   int
+	poller poller
+}
+
+// NewTickerWatcher constructs a new TickerWatcher which polls every provided
+// pair once per interval. The interval is raised if required so that the
+// watcher stays within its share of the client's rate limit.
+func NewTickerWatcher(c *Client, interval time.Duration, pairs ...MarketPair) *TickerWatcher {
+	if min := time.Duration(len(pairs)) * tickerPairInterval; interval < min {
+		interval = min
+	}
+
+	return &TickerWatcher{
+		StaleAfter: DefaultTickerStaleAfter,
+		c:          c,
+		pairs:      pairs,
+		interval:   interval,
+		subs:       make(map[int]chan TickerEvent),
+		state:      make(map[MarketPair]*tickerState),
+	}
+}
+
+// Subscribe returns a channel which receives ticker events, and a function
+// which cancels the subscription and closes the channel. Events are dropped
+// for subscribers which are not keeping up.
+func (tw *TickerWatcher) Subscribe() (<-chan TickerEvent, func()) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	id := tw.nextID
+	tw.nextID++
+
+	ch := make(chan TickerEvent, tickerSubscriberBuffer)
+	tw.subs[id] = ch
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			tw.mu.Lock()
+			defer tw.mu.Unlock()
+
+			if _, ok := tw.subs[id]; ok {
+				delete(tw.subs, id)
+				close(ch)
+			}
+		})
+	}
+}
+
+// Last returns the most recent tick received for the pair, if any.
+func (tw *TickerWatcher) Last(pair MarketPair) (*MarketTickResponse, bool) {
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	st, ok := tw.state[pair]
+	if !ok {
+		return nil, false
+	}
+
+	return st.tick, true
+}
+
+// Start begins polling in the background, spreading the polls of each pair
+// evenly over the interval. It does nothing if the watcher is already running.
+func (tw *TickerWatcher) Start() {
+	if len(tw.pairs) == 0 {
+		return
+	}
+
+	tw.poller.start(tw.interval/time.Duration(len(tw.pairs)), true, func(<-chan struct{}) {
+		tw.poll(tw.pairs[tw.next])
+		tw.next = (tw.next + 1) % len(tw.pairs)
+	})
+}
+
+// Stop stops polling and waits for any poll in progress to finish.
+// Subscriptions remain open so that the watcher can be started again.
+func (tw *TickerWatcher) Stop() {
+	tw.poller.halt()
+}
+
+// poll fetches the tick for a single pair and publishes any resulting event.
+func (tw *TickerWatcher) poll(pair MarketPair) {
+	tick, err := tw.c.MarketTick(pair.Instrument, pair.Currency)
+	now := time.Now()
+
+	tw.mu.Lock()
+	defer tw.mu.Unlock()
+
+	if err != nil {
+		tw.publish(TickerEvent{Pair: pair, Err: err})
+		return
+	}
+
+	st, ok := tw.state[pair]
+	if !ok {
+		tw.state[pair] = &tickerState{tick: tick, advanced: now}
+		tw.publish(TickerEvent{Pair: pair, Tick: tick})
+		return
+	}
+
+	prev := st.tick
+	st.tick = tick
+
+	if tick.Timestamp > prev.Timestamp {
+		st.advanced = now
+	}
+
+	switch {
+	case tick.Bid != prev.Bid || tick.Ask != prev.Ask || tick.Last != prev.Last:
+		st.stale = false
+		tw.publish(TickerEvent{Pair: pair, Tick: tick})
+	case tick.Timestamp > prev.Timestamp:
+		// Tell subscribers that a stale feed has recovered, even though
+		// its prices have not changed.
+		if st.stale {
+			st.stale = false
+			tw.publish(TickerEvent{Pair: pair, Tick: tick})
+		}
+	case !st.stale && tw.StaleAfter > 0 && now.Sub(st.advanced) >= tw.StaleAfter:
+		st.stale = true
+		tw.publish(TickerEvent{Pair: pair, Tick: tick, Stale: true})
+	}
+}
+
+// publish sends an event to every subscriber without blocking. It must be
+// called with the lock held.
+func (tw *TickerWatcher) publish(ev TickerEvent) {
+	for _, ch := range tw.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package btcmarkets
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestTickerWatcherStaleRecovery(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		timestamp int64 = 1
+	)
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		writeJSON(t, w, MarketTickResponse{Bid: 10, Ask: 11, Last: 10, Instrument: InstrumentBitcoin, Currency: CurrencyAUD, Timestamp: timestamp})
+	})
+
+	pair := MarketPair{Instrument: InstrumentBitcoin, Currency: CurrencyAUD}
+	tw := NewTickerWatcher(c, 0, pair)
+	tw.StaleAfter = 1
+
+	events, cancel := tw.Subscribe()
+	defer cancel()
+
+	// Polled directly, as the watcher is never started.
+	tw.poll(pair)
+	tw.poll(pair)
+
+	mu.Lock()
+	timestamp = 2
+	mu.Unlock()
+	tw.poll(pair)
+
+	for _, stale := range []bool{false, true, false} {
+		select {
+		case ev := <-events:
+			if ev.Stale != stale || ev.Tick == nil {
+				t.Errorf("event = %+v, want Stale %t", ev, stale)
+			}
+		default:
+			t.Fatalf("no event, want Stale %t", stale)
+		}
+	}
+
+	select {
+	case ev := <-events:
+		t.Errorf("unexpected event %+v", ev)
+	default:
+	}
+}
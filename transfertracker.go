@@ -0,0 +1,250 @@
+package btcmarkets
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultTransferNotFoundAfter is the default time a TransferTracker waits for
+// a tracked transfer to appear in the fund transfer history.
+const DefaultTransferNotFoundAfter = 10 * time.Minute
+
+const (
+	// transferTrackerInterval is the minimum time between fund transfer history
+	// requests made by a TransferTracker.
+	transferTrackerInterval = 5 * time.Second
+
+	// transferTrackerEventBuffer is the number of events buffered by a
+	// TransferTracker.
+	transferTrackerEventBuffer = 64
+)
+
+// TransferEvent is sent by a TransferTracker when a tracked transfer changes
+// status or its fee changes. Previous is empty the first time a transfer is
+// seen. Transfer.Fee is the fee actually charged, as reported by the API. If a
+// tracked transfer is not found in the history, Err is a
+// *TransferNotFoundError and only Transfer.FundTransferID is set.
+type TransferEvent struct {
+	Transfer FundTransferDataItem
+	Previous TransferStatus
+	Err      error
+}
+
+// TransferNotFoundError is reported when a tracked transfer does not appear in
+// the fund transfer history within the tracker's NotFoundAfter.
+type TransferNotFoundError struct {
+	TransferID TransferID
+	Since      time.Time
+}
+
+func (e *TransferNotFoundError) Error() string {
+	return fmt.Sprintf("Transfer %d not found in history since %s", e.TransferID, e.Since.Format(time.RFC3339))
+}
+
+// trackedTransfer is the last known state of a transfer tracked by a
+// TransferTracker.
+type trackedTransfer struct {
+	added  time.Time
+	seen   bool
+	status TransferStatus
+	fee    AmountWhole
+	done   chan FundTransferDataItem
+}
+
+// TransferTracker watches withdrawals (or deposits) until they reach a terminal
+// status, polling the fund transfer history for every tracked transfer. It is
+// concurrency safe.
+//
+// Events are delivered to OnEvent if it is set, and otherwise on the Events
+// channel, which must then be drained while the tracker is running.
+type TransferTracker struct {
+	// OnEvent, if set, is called from the polling goroutine with each event
+	// instead of sending it on the Events channel. It must be set before
+	// Start.
+	OnEvent func(TransferEvent)
+
+	// NotFoundAfter is the time after which a tracked transfer which has not
+	// appeared in the history is reported with a *TransferNotFoundError and
+	// no longer tracked.
+	NotFoundAfter time.Duration
+
+	c        *Client
+	interval time.Duration
+
+	mu        sync.Mutex
+	transfers map[TransferID]*trackedTransfer
+	events    chan TransferEvent
+	poller    poller
+}
+
+// NewTransferTracker constructs a new TransferTracker which polls tracked
+// transfers once per interval.
+func NewTransferTracker(c *Client, interval time.Duration) *TransferTracker {
+	if interval < transferTrackerInterval {
+		interval = transferTrackerInterval
+	}
+
+	return &TransferTracker{
+		NotFoundAfter: DefaultTransferNotFoundAfter,
+
+		c:         c,
+		interval:  interval,
+		transfers: make(map[TransferID]*trackedTransfer),
+		events:    make(chan TransferEvent, transferTrackerEventBuffer),
+	}
+}
+
+// Track starts tracking a transfer, such as the FundTransferID returned by
+// WithdrawCrypto or WithdrawEFT. The returned channel receives the transfer
+// once it reaches a terminal status, and is then closed. It is closed without
+// a value if the transfer is untracked or not found. Tracking a transfer which
+// is already tracked returns the existing channel.
+func (tt *TransferTracker) Track(id TransferID) <-chan FundTransferDataItem {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	if t, ok := tt.transfers[id]; ok {
+		return t.done
+	}
+
+	t := &trackedTransfer{
+		added: time.Now(),
+		done:  make(chan FundTransferDataItem, 1),
+	}
+	tt.transfers[id] = t
+
+	return t.done
+}
+
+// Untrack stops tracking a transfer, closing its channel without a value.
+func (tt *TransferTracker) Untrack(id TransferID) {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	if t, ok := tt.transfers[id]; ok {
+		delete(tt.transfers, id)
+		close(t.done)
+	}
+}
+
+// Tracking returns the number of transfers currently being tracked.
+func (tt *TransferTracker) Tracking() int {
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	return len(tt.transfers)
+}
+
+// Events returns the channel on which transfer events are delivered when
+// OnEvent is not set.
+func (tt *TransferTracker) Events() <-chan TransferEvent {
+	return tt.events
+}
+
+// Start begins polling in the background. It does nothing if the tracker is
+// already running.
+func (tt *TransferTracker) Start() {
+	tt.poller.start(tt.interval, true, func(stop <-chan struct{}) {
+		for _, ev := range tt.poll() {
+			if tt.OnEvent != nil {
+				tt.OnEvent(ev)
+				continue
+			}
+
+			select {
+			case tt.events <- ev:
+			case <-stop:
+				return
+			}
+		}
+	})
+}
+
+// Stop stops polling and waits for any poll in progress to finish. Tracked
+// transfers remain tracked so that the tracker can be started again.
+func (tt *TransferTracker) Stop() {
+	tt.poller.halt()
+}
+
+// poll fetches the fund transfer history from the oldest tracked transfer
+// onwards and returns the resulting events.
+func (tt *TransferTracker) poll() []TransferEvent {
+	tt.mu.Lock()
+	var oldest TransferID
+	for id := range tt.transfers {
+		if oldest == 0 || id < oldest {
+			oldest = id
+		}
+	}
+	tt.mu.Unlock()
+
+	if oldest == 0 {
+		return nil
+	}
+
+	var events []TransferEvent
+
+	it := tt.c.FundTransferHistoryAll(oldest - 1)
+	for it.Next() {
+		tt.mu.Lock()
+		ev, ok := tt.update(it.Transfer())
+		tt.mu.Unlock()
+
+		if ok {
+			events = append(events, ev)
+		}
+	}
+
+	if err := it.Err(); err != nil {
+		return append(events, TransferEvent{Err: err})
+	}
+
+	// Only a complete walk of the history shows that a transfer is missing.
+	tt.mu.Lock()
+	defer tt.mu.Unlock()
+
+	now := time.Now()
+	for id, t := range tt.transfers {
+		if t.seen || tt.NotFoundAfter <= 0 || now.Sub(t.added) < tt.NotFoundAfter {
+			continue
+		}
+
+		delete(tt.transfers, id)
+		close(t.done)
+
+		events = append(events, TransferEvent{
+			Transfer: FundTransferDataItem{FundTransferID: id},
+			Err:      &TransferNotFoundError{TransferID: id, Since: t.added},
+		})
+	}
+
+	return events
+}
+
+// update applies the latest state of a transfer, returning an event if the
+// transfer is tracked and changed. It must be called with the lock held.
+func (tt *TransferTracker) update(ft FundTransferDataItem) (TransferEvent, bool) {
+	t, ok := tt.transfers[ft.FundTransferID]
+	if !ok {
+		return TransferEvent{}, false
+	}
+
+	ev := TransferEvent{
+		Transfer: ft,
+		Previous: t.status,
+	}
+
+	t.seen = true
+	changed := ft.Status != t.status || ft.Fee != t.fee
+	t.status = ft.Status
+	t.fee = ft.Fee
+
+	if ft.Status.IsTerminal() {
+		delete(tt.transfers, ft.FundTransferID)
+		t.done <- ft
+		close(t.done)
+	}
+
+	return ev, changed
+}
@@ -0,0 +1,507 @@
+package btcmarkets
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// TriggerKind is the condition under which a Trigger fires.
+type TriggerKind string
+
+// Enumerated trigger kinds.
+const (
+	// TriggerStopLoss fires when the price moves against the position to the
+	// trigger price. An Ask stop-loss fires at or below the trigger price, and
+	// a Bid stop-loss (a buy stop) fires at or above it.
+	TriggerStopLoss TriggerKind = "StopLoss"
+
+	// TriggerTakeProfit fires when the price moves in favour of the position
+	// to the trigger price. An Ask take-profit fires at or above the trigger
+	// price, and a Bid take-profit fires at or below it.
+	TriggerTakeProfit TriggerKind = "TakeProfit"
+
+	// TriggerTrailingStop is a stop-loss whose trigger price follows the best
+	// price seen since it was added, staying TrailAmount behind it.
+	TriggerTrailingStop TriggerKind = "TrailingStop"
+)
+
+// TriggerState is the state of a Trigger.
+type TriggerState string
+
+// Enumerated trigger states.
+const (
+	// TriggerPending is a trigger waiting for its condition.
+	TriggerPending TriggerState = "Pending"
+
+	// TriggerSubmitting is a trigger whose condition has been met and whose
+	// order is being submitted.
+	TriggerSubmitting TriggerState = "Submitting"
+
+	// TriggerFired is a trigger whose order has been placed.
+	TriggerFired TriggerState = "Fired"
+
+	// TriggerCancelled is a trigger cancelled by the client, or by another
+	// trigger in its OCO group firing.
+	TriggerCancelled TriggerState = "Cancelled"
+
+	// TriggerFailed is a trigger whose order could not be placed.
+	TriggerFailed TriggerState = "Failed"
+)
+
+// Trigger is an order which is held client-side and submitted with OrderCreate
+// once its condition is met by the last price of its market.
+type Trigger struct {
+	ID         string      `json:"id"`
+	Kind       TriggerKind `json:"kind"`
+	Instrument Instrument  `json:"instrument"`
+	Currency   Currency    `json:"currency"`
+	OrderSide  OrderSide   `json:"orderSide"`
+	Volume     AmountWhole `json:"volume"`
+
+	// TriggerPrice is the price at which stop-loss and take-profit triggers fire.
+	TriggerPrice AmountWhole `json:"triggerPrice"`
+
+	// TrailAmount is the distance a trailing stop keeps behind the best price.
+	TrailAmount AmountWhole `json:"trailAmount"`
+
+	// LimitPrice is the price of the order placed when the trigger fires. A
+	// market order is placed when it is zero.
+	LimitPrice AmountWhole `json:"limitPrice"`
+
+	// OCOGroup links triggers so that when one fires the others are cancelled
+	// (one-cancels-other). Triggers with an empty group are independent.
+	OCOGroup string `json:"ocoGroup"`
+
+	State   TriggerState `json:"state"`
+	Extreme AmountWhole  `json:"extreme"`
+	OrderID OrderID      `json:"orderId"`
+	Error   string       `json:"error"`
+	Created time.Time    `json:"created"`
+	Updated time.Time    `json:"updated"`
+}
+
+// Validate checks the trigger has everything required to fire.
+func (t *Trigger) Validate() error {
+	switch t.Kind {
+	case TriggerStopLoss, TriggerTakeProfit:
+		if t.TriggerPrice <= 0 {
+			return fmt.Errorf("Trigger %s requires a trigger price", t.Kind)
+		}
+	case TriggerTrailingStop:
+		if t.TrailAmount <= 0 {
+			return errors.New("Trailing stop requires a trail amount")
+		}
+	default:
+		return fmt.Errorf("Unknown trigger kind %q", t.Kind)
+	}
+
+	if t.OrderSide != Ask && t.OrderSide != Bid {
+		return fmt.Errorf("Unknown order side %q", t.OrderSide)
+	}
+
+	if t.Volume <= 0 {
+		return errors.New("Trigger volume must be greater than zero")
+	}
+
+	if t.Instrument == "" || t.Currency == "" {
+		return errors.New("Trigger requires an instrument and currency")
+	}
+
+	return nil
+}
+
+// met reports whether the trigger's condition is met by the last price,
+// updating the best price seen by trailing stops. It returns true for changed
+// if the trigger was modified.
+func (t *Trigger) met(last AmountWhole) (fire, changed bool) {
+	switch t.Kind {
+	case TriggerStopLoss:
+		if t.OrderSide == Ask {
+			return last <= t.TriggerPrice, false
+		}
+		return last >= t.TriggerPrice, false
+
+	case TriggerTakeProfit:
+		if t.OrderSide == Ask {
+			return last >= t.TriggerPrice, false
+		}
+		return last <= t.TriggerPrice, false
+
+	case TriggerTrailingStop:
+		if t.Extreme == 0 || (t.OrderSide == Ask && last > t.Extreme) || (t.OrderSide == Bid && last < t.Extreme) {
+			t.Extreme = last
+			changed = true
+		}
+
+		if t.OrderSide == Ask {
+			return last <= t.Extreme-t.TrailAmount, changed
+		}
+		return last >= t.Extreme+t.TrailAmount, changed
+	}
+
+	return false, false
+}
+
+// TriggerStore persists triggers so that they survive restarts.
+type TriggerStore interface {
+	Load() ([]Trigger, error)
+	Save([]Trigger) error
+}
+
+// FileTriggerStore is a TriggerStore which keeps triggers in a JSON file.
+type FileTriggerStore struct {
+	Path string
+}
+
+// Load reads the triggers from the file. A missing file holds no triggers.
+func (fs *FileTriggerStore) Load() ([]Trigger, error) {
+	data, err := os.ReadFile(fs.Path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read triggers (%s)", err.Error())
+	}
+
+	var triggers []Trigger
+	if err = json.Unmarshal(data, &triggers); err != nil {
+		return nil, fmt.Errorf("Failed to decode triggers (%s)", err.Error())
+	}
+
+	return triggers, nil
+}
+
+// Save writes the triggers to the file, replacing it atomically so that a
+// crash part way through cannot lose the existing triggers.
+func (fs *FileTriggerStore) Save(triggers []Trigger) error {
+	data, err := json.MarshalIndent(triggers, "", "\t")
+	if err != nil {
+		return fmt.Errorf("Failed to encode triggers (%s)", err.Error())
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".*")
+	if err != nil {
+		return fmt.Errorf("Failed to save triggers (%s)", err.Error())
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("Failed to save triggers (%s)", err.Error())
+	}
+
+	if err = tmp.Close(); err != nil {
+		return fmt.Errorf("Failed to save triggers (%s)", err.Error())
+	}
+
+	if err = os.Rename(tmp.Name(), fs.Path); err != nil {
+		return fmt.Errorf("Failed to save triggers (%s)", err.Error())
+	}
+
+	return nil
+}
+
+// TriggerEngine holds client-side stop-loss, take-profit and trailing-stop
+// orders, and submits them with OrderCreate when ticks meet their conditions.
+// Every change of state is persisted to its store. It is concurrency safe.
+type TriggerEngine struct {
+	c     *Client
+	store TriggerStore
+
+	mu       sync.Mutex
+	triggers map[string]*Trigger
+}
+
+// NewTriggerEngine constructs a new TriggerEngine, loading any triggers which
+// were persisted in the store. Triggers which were part way through submission
+// when the engine last stopped are marked as failed, as it is unknown whether
+// their order was placed.
+func NewTriggerEngine(c *Client, store TriggerStore) (*TriggerEngine, error) {
+	triggers, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+
+	te := &TriggerEngine{
+		c:        c,
+		store:    store,
+		triggers: make(map[string]*Trigger, len(triggers)),
+	}
+
+	for i := range triggers {
+		t := triggers[i]
+		if t.State == TriggerSubmitting {
+			t.State = TriggerFailed
+			t.Error = "Engine stopped while submitting, check open orders"
+		}
+		te.triggers[t.ID] = &t
+	}
+
+	return te, nil
+}
+
+// Add validates and adds a pending trigger, returning its ID. An ID is
+// generated if the trigger does not have one.
+func (te *TriggerEngine) Add(t Trigger) (string, error) {
+	if err := t.Validate(); err != nil {
+		return "", err
+	}
+
+	if t.ID == "" {
+		id, err := randomID()
+		if err != nil {
+			return "", err
+		}
+		t.ID = id
+	}
+
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	if _, ok := te.triggers[t.ID]; ok {
+		return "", fmt.Errorf("Trigger %s already exists", t.ID)
+	}
+
+	now := time.Now()
+	t.State = TriggerPending
+	t.Extreme = 0
+	t.OrderID = 0
+	t.Error = ""
+	t.Created = now
+	t.Updated = now
+	te.triggers[t.ID] = &t
+
+	if err := te.save(); err != nil {
+		delete(te.triggers, t.ID)
+		return "", err
+	}
+
+	return t.ID, nil
+}
+
+// Cancel cancels a pending trigger.
+func (te *TriggerEngine) Cancel(id string) error {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	t, ok := te.triggers[id]
+	if !ok {
+		return fmt.Errorf("Trigger %s does not exist", id)
+	}
+
+	if t.State != TriggerPending {
+		return fmt.Errorf("Trigger %s is %s and cannot be cancelled", id, t.State)
+	}
+
+	saved := *t
+	t.State = TriggerCancelled
+	t.Updated = time.Now()
+
+	if err := te.save(); err != nil {
+		*t = saved
+		return err
+	}
+
+	return nil
+}
+
+// Remove forgets a trigger which is no longer pending.
+func (te *TriggerEngine) Remove(id string) error {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	t, ok := te.triggers[id]
+	if !ok {
+		return nil
+	}
+
+	if t.State == TriggerPending || t.State == TriggerSubmitting {
+		return fmt.Errorf("Trigger %s is %s and cannot be removed", id, t.State)
+	}
+
+	delete(te.triggers, id)
+
+	return te.save()
+}
+
+// Trigger returns a copy of the trigger with the provided ID.
+func (te *TriggerEngine) Trigger(id string) (Trigger, bool) {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	t, ok := te.triggers[id]
+	if !ok {
+		return Trigger{}, false
+	}
+
+	return *t, true
+}
+
+// Triggers returns a copy of every trigger, ordered by creation time.
+func (te *TriggerEngine) Triggers() []Trigger {
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	return te.list()
+}
+
+// OnTick evaluates the pending triggers of the tick's market against its last
+// price, submitting the orders of any which fire.
+func (te *TriggerEngine) OnTick(tick *MarketTickResponse) error {
+	last := tick.Last.ToAmountWhole()
+	if last <= 0 {
+		return nil
+	}
+
+	te.mu.Lock()
+
+	var (
+		fired   []*Trigger
+		saved   = make(map[*Trigger]Trigger)
+		changed bool
+	)
+	for _, t := range te.triggers {
+		if t.State != TriggerPending || t.Instrument != tick.Instrument || t.Currency != tick.Currency {
+			continue
+		}
+
+		if _, ok := saved[t]; !ok {
+			saved[t] = *t
+		}
+
+		fire, modified := t.met(last)
+		if modified {
+			changed = true
+		}
+		if !fire {
+			continue
+		}
+
+		now := time.Now()
+		t.State = TriggerSubmitting
+		t.Updated = now
+		fired = append(fired, t)
+		changed = true
+
+		// Cancel the rest of the OCO group before anything is submitted so
+		// that only one of the group can ever reach the exchange.
+		if t.OCOGroup != "" {
+			for _, other := range te.triggers {
+				if other != t && other.OCOGroup == t.OCOGroup && other.State == TriggerPending {
+					if _, ok := saved[other]; !ok {
+						saved[other] = *other
+					}
+					other.State = TriggerCancelled
+					other.Updated = now
+				}
+			}
+		}
+	}
+
+	var err error
+	if changed {
+		err = te.save()
+	}
+
+	if err != nil {
+		// Nothing is submitted unless its state was saved, so return the
+		// triggers to how they were, including the best price seen by
+		// trailing stops, to be evaluated again on the next tick.
+		for t, before := range saved {
+			*t = before
+		}
+	}
+
+	te.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, t := range fired {
+		if serr := te.submit(t); serr != nil && err == nil {
+			err = serr
+		}
+	}
+
+	return err
+}
+
+// Watch evaluates triggers against every tick received from a TickerWatcher
+// subscription until the channel is closed. Errors are passed to onError if
+// it is not nil.
+func (te *TriggerEngine) Watch(events <-chan TickerEvent, onError func(error)) {
+	for ev := range events {
+		if ev.Err != nil || ev.Tick == nil {
+			continue
+		}
+
+		if err := te.OnTick(ev.Tick); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// submit places the order of a fired trigger and records the outcome.
+func (te *TriggerEngine) submit(t *Trigger) error {
+	te.mu.Lock()
+	req := *t
+	te.mu.Unlock()
+
+	ordertype, price := Limit, req.LimitPrice
+	if price == 0 {
+		ordertype = Market
+	}
+
+	ocr, err := te.c.OrderCreate(req.Currency, req.Instrument, price, req.Volume, req.OrderSide, ordertype, req.ID)
+	if err == nil && !ocr.Success {
+		err = fmt.Errorf("Failed to place order for trigger %s (%d: %s)", req.ID, ocr.ErrorCode, ocr.ErrorMessage)
+	}
+
+	te.mu.Lock()
+	defer te.mu.Unlock()
+
+	t.Updated = time.Now()
+	if err != nil {
+		t.State = TriggerFailed
+		t.Error = err.Error()
+	} else {
+		t.State = TriggerFired
+		t.OrderID = ocr.ID
+	}
+
+	if serr := te.save(); serr != nil {
+		return serr
+	}
+
+	return err
+}
+
+// list returns a copy of every trigger ordered by creation time. It must be
+// called with the lock held.
+func (te *TriggerEngine) list() []Trigger {
+	out := make([]Trigger, 0, len(te.triggers))
+	for _, t := range te.triggers {
+		out = append(out, *t)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Created.Equal(out[j].Created) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].Created.Before(out[j].Created)
+	})
+
+	return out
+}
+
+// save persists every trigger. It must be called with the lock held.
+func (te *TriggerEngine) save() error {
+	return te.store.Save(te.list())
+}
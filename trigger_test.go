@@ -0,0 +1,79 @@
+package btcmarkets
+
+import (
+	"errors"
+	"testing"
+)
+
+// memoryTriggerStore is a TriggerStore which keeps triggers in memory, failing
+// every save while fail is set.
+type memoryTriggerStore struct {
+	saved []Trigger
+	fail  bool
+}
+
+func (s *memoryTriggerStore) Load() ([]Trigger, error) {
+	return s.saved, nil
+}
+
+func (s *memoryTriggerStore) Save(triggers []Trigger) error {
+	if s.fail {
+		return errors.New("disk full")
+	}
+
+	s.saved = triggers
+	return nil
+}
+
+func TestTriggerEngineSaveFailure(t *testing.T) {
+	store := &memoryTriggerStore{}
+	te, err := NewTriggerEngine(nil, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trailing := Trigger{
+		ID:          "trail",
+		Kind:        TriggerTrailingStop,
+		Instrument:  InstrumentBitcoin,
+		Currency:    CurrencyAUD,
+		OrderSide:   Ask,
+		Volume:      100000000,
+		TrailAmount: 10000000000,
+	}
+
+	store.fail = true
+	if _, err := te.Add(trailing); err == nil {
+		t.Fatal("Add error = nil, want the save error")
+	}
+	if _, ok := te.Trigger("trail"); ok {
+		t.Fatal("trigger was added although it was not saved")
+	}
+
+	store.fail = false
+	if _, err := te.Add(trailing); err != nil {
+		t.Fatal(err)
+	}
+
+	tick := &MarketTickResponse{Instrument: InstrumentBitcoin, Currency: CurrencyAUD, Last: 10000}
+	if err := te.OnTick(tick); err != nil {
+		t.Fatal(err)
+	}
+
+	// A higher price moves the trailing stop, but is not saved.
+	store.fail = true
+	tick.Last = 11000
+	if err := te.OnTick(tick); err == nil {
+		t.Fatal("OnTick error = nil, want the save error")
+	}
+	if got, _ := te.Trigger("trail"); got.Extreme != 1000000000000 || got.State != TriggerPending {
+		t.Errorf("after failed save extreme = %d state = %s, want 1000000000000 and Pending", got.Extreme, got.State)
+	}
+
+	if err := te.Cancel("trail"); err == nil {
+		t.Fatal("Cancel error = nil, want the save error")
+	}
+	if got, _ := te.Trigger("trail"); got.State != TriggerPending {
+		t.Errorf("after failed cancel state = %s, want Pending", got.State)
+	}
+}
@@ -0,0 +1,358 @@
+package btcmarkets
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultWithdrawalTTL is the default time a prepared withdrawal may wait for
+// confirmation before it expires.
+const DefaultWithdrawalTTL = 10 * time.Minute
+
+// WithdrawalRejectedError is returned when a WithdrawalGuard refuses a
+// withdrawal, before any request is sent.
+type WithdrawalRejectedError struct {
+	Currency Currency
+	Address  string
+	Reason   string
+}
+
+func (e *WithdrawalRejectedError) Error() string {
+	return fmt.Sprintf("Withdrawal of %s to %s rejected (%s)", e.Currency, e.Address, e.Reason)
+}
+
+// WhitelistEntry is a destination address which withdrawals may be made to.
+// When Tag is set the entry only allows that destination tag, so an address
+// shared by several accounts (such as an exchange's) can be allowed once per
+// tag. An entry without a Tag allows any tag, or requires one when RequireTag
+// is set.
+type WhitelistEntry struct {
+	Currency   Currency
+	Address    string
+	Label      string
+	RequireTag bool
+	Tag        string
+}
+
+// PendingWithdrawal is a withdrawal which has been prepared by a
+// WithdrawalGuard and is waiting to be confirmed.
+type PendingWithdrawal struct {
+	ID       string
+	Currency Currency
+	Address  string
+	Tag      string
+	Label    string
+	Amount   AmountWhole
+	Prepared time.Time
+	Expires  time.Time
+}
+
+// WithdrawalGuard is a safeguard layer for crypto withdrawals. Withdrawals are
+// only made to whitelisted addresses, within a daily limit per currency, and in
+// two phases: Prepare checks and reserves the withdrawal, and Confirm asks the
+// Approver before the signed request is sent. It is concurrency safe.
+type WithdrawalGuard struct {
+	// Approver, if set, is called by Confirm and must return nil for the
+	// withdrawal to be sent, such as after asking a human for approval.
+	Approver func(*PendingWithdrawal) error
+
+	// TTL is the time a prepared withdrawal may wait for confirmation.
+	TTL time.Duration
+
+	c *Client
+
+	mu        sync.Mutex
+	whitelist map[Currency]map[CryptoDestination]WhitelistEntry
+	limits    map[Currency]AmountWhole
+	day       string
+	spent     map[Currency]AmountWhole
+	pending   map[string]*PendingWithdrawal
+	approving map[string]*PendingWithdrawal
+}
+
+// NewWithdrawalGuard constructs a new WithdrawalGuard with an empty whitelist,
+// which refuses every withdrawal until addresses are allowed.
+func NewWithdrawalGuard(c *Client) *WithdrawalGuard {
+	return &WithdrawalGuard{
+		TTL:       DefaultWithdrawalTTL,
+		c:         c,
+		whitelist: make(map[Currency]map[CryptoDestination]WhitelistEntry),
+		limits:    make(map[Currency]AmountWhole),
+		spent:     make(map[Currency]AmountWhole),
+		pending:   make(map[string]*PendingWithdrawal),
+		approving: make(map[string]*PendingWithdrawal),
+	}
+}
+
+// Allow adds an address, or an address and tag, to the whitelist, returning an
+// *AddressError if the address or tag is not valid for its currency.
+func (g *WithdrawalGuard) Allow(entry WhitelistEntry) error {
+	dest := CryptoDestination{Address: entry.Address, Tag: entry.Tag}
+	if err := dest.Validate(entry.Currency); err != nil {
+		return err
+	}
+
+	if entry.RequireTag && !destinationTags[entry.Currency] {
+		return &AddressError{Currency: entry.Currency, Address: entry.Address, Reason: "Currency does not support destination tags"}
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.whitelist[entry.Currency] == nil {
+		g.whitelist[entry.Currency] = make(map[CryptoDestination]WhitelistEntry)
+	}
+	g.whitelist[entry.Currency][dest] = entry
+
+	return nil
+}
+
+// Revoke removes an address from the whitelist. An address with a destination
+// tag, in the form "address?dt=tag", removes only the entry for that tag, and
+// otherwise every entry of the address is removed.
+func (g *WithdrawalGuard) Revoke(currency Currency, address string) {
+	dest := ParseCryptoDestination(address)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for d := range g.whitelist[currency] {
+		if d == dest || dest.Tag == "" && d.Address == dest.Address {
+			delete(g.whitelist[currency], d)
+		}
+	}
+}
+
+// SetDailyLimit sets the most of a currency which may be withdrawn per day.
+// Currencies without a limit may not be withdrawn at all.
+func (g *WithdrawalGuard) SetDailyLimit(currency Currency, amount AmountWhole) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.limits[currency] = amount
+}
+
+// Remaining returns how much more of the currency may be withdrawn today,
+// allowing for withdrawals which are prepared or awaiting approval.
+func (g *WithdrawalGuard) Remaining(currency Currency) AmountWhole {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	return g.remaining(currency)
+}
+
+// Prepare checks a withdrawal against the whitelist, including any tag the
+// whitelisted address requires, and the daily limit, and reserves it until it
+// is confirmed or expires.
+func (g *WithdrawalGuard) Prepare(amount AmountWhole, currency Currency, dest CryptoDestination) (*PendingWithdrawal, error) {
+	address := dest.String()
+
+	if amount <= 0 {
+		return nil, &WithdrawalRejectedError{Currency: currency, Address: address, Reason: "Amount must be greater than zero"}
+	}
+
+	if err := dest.Validate(currency); err != nil {
+		return nil, &WithdrawalRejectedError{Currency: currency, Address: address, Reason: err.Error()}
+	}
+
+	id, err := randomID()
+	if err != nil {
+		return nil, err
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	entry, reason := g.whitelisted(currency, dest)
+	if reason != "" {
+		return nil, &WithdrawalRejectedError{Currency: currency, Address: address, Reason: reason}
+	}
+
+	if remaining := g.remaining(currency); amount > remaining {
+		return nil, &WithdrawalRejectedError{
+			Currency: currency,
+			Address:  address,
+			Reason:   fmt.Sprintf("Amount of %f exceeds the %f remaining of today's limit", amount.ToAmountDecimal(), remaining.ToAmountDecimal()),
+		}
+	}
+
+	now := time.Now()
+	pw := &PendingWithdrawal{
+		ID:       id,
+		Currency: currency,
+		Address:  dest.Address,
+		Tag:      dest.Tag,
+		Label:    entry.Label,
+		Amount:   amount,
+		Prepared: now,
+		Expires:  now.Add(g.TTL),
+	}
+	g.pending[id] = pw
+
+	cp := *pw
+	return &cp, nil
+}
+
+// Confirm asks the Approver to approve a prepared withdrawal and, if it does,
+// sends it with WithdrawCryptoTo. The withdrawal stays reserved against the
+// daily limit while the Approver runs. The whitelist and limit are checked
+// again afterwards, in case the address was revoked, its tag changed or the
+// limit lowered after the withdrawal was prepared.
+func (g *WithdrawalGuard) Confirm(id string) (*FundTransferWithdrawCryptoResponse, error) {
+	g.mu.Lock()
+	pw, ok := g.pending[id]
+	if ok {
+		delete(g.pending, id)
+		if !time.Now().After(pw.Expires) {
+			g.approving[id] = pw
+		}
+	}
+	approver := g.Approver
+	g.mu.Unlock()
+
+	if !ok {
+		return nil, fmt.Errorf("No prepared withdrawal %s", id)
+	}
+
+	if time.Now().After(pw.Expires) {
+		return nil, &WithdrawalRejectedError{Currency: pw.Currency, Address: pw.Address, Reason: "Prepared withdrawal has expired"}
+	}
+
+	if approver != nil {
+		if err := approver(pw); err != nil {
+			g.mu.Lock()
+			delete(g.approving, id)
+			g.mu.Unlock()
+			return nil, &WithdrawalRejectedError{Currency: pw.Currency, Address: pw.Address, Reason: "Not approved: " + err.Error()}
+		}
+	}
+
+	// Move the withdrawal from its reservation to the amount spent, counting
+	// it before sending so that the limit cannot be exceeded by withdrawals
+	// confirmed concurrently.
+	g.mu.Lock()
+	delete(g.approving, id)
+	dest := CryptoDestination{Address: pw.Address, Tag: pw.Tag}
+	_, reason := g.whitelisted(pw.Currency, dest)
+	if reason != "" {
+		reason = "No longer allowed: " + reason
+	} else if remaining := g.remaining(pw.Currency); pw.Amount > remaining {
+		reason = fmt.Sprintf("Amount of %f exceeds the %f remaining of today's limit", pw.Amount.ToAmountDecimal(), remaining.ToAmountDecimal())
+	} else {
+		g.spent[pw.Currency] += pw.Amount
+	}
+	day := g.day
+	g.mu.Unlock()
+
+	if reason != "" {
+		return nil, &WithdrawalRejectedError{Currency: pw.Currency, Address: pw.Address, Reason: reason}
+	}
+
+	res, err := g.c.WithdrawCryptoTo(pw.Amount, pw.Currency, dest)
+	if err != nil || !res.Success {
+		// The withdrawal did not go ahead, so return it to the limit, unless
+		// the day has changed and the limit has already been reset. A
+		// network error leaves it counted, as it may have been sent.
+		if err == nil {
+			g.mu.Lock()
+			g.rollDay()
+			if g.day == day {
+				g.spent[pw.Currency] -= pw.Amount
+			}
+			g.mu.Unlock()
+			err = fmt.Errorf("Failed to withdraw (%s: %s)", res.ErrorCode, res.ErrorMessage)
+		}
+		return res, err
+	}
+
+	return res, nil
+}
+
+// Discard abandons a prepared withdrawal, releasing its reservation.
+func (g *WithdrawalGuard) Discard(id string) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, ok := g.pending[id]; !ok {
+		return errors.New("No prepared withdrawal " + id)
+	}
+	delete(g.pending, id)
+
+	return nil
+}
+
+// whitelisted returns the whitelist entry allowing a destination, preferring an
+// entry for its exact tag, or the reason it is not allowed. It must be called
+// with the lock held.
+func (g *WithdrawalGuard) whitelisted(currency Currency, dest CryptoDestination) (WhitelistEntry, string) {
+	entries := g.whitelist[currency]
+
+	if dest.Tag != "" {
+		if entry, ok := entries[dest]; ok {
+			return entry, ""
+		}
+	}
+
+	entry, ok := entries[CryptoDestination{Address: dest.Address}]
+	if !ok {
+		for d := range entries {
+			if d.Address == dest.Address && dest.Tag == "" {
+				return WhitelistEntry{}, "Address requires a destination tag"
+			}
+			if d.Address == dest.Address {
+				return WhitelistEntry{}, "Destination tag does not match a whitelisted tag"
+			}
+		}
+
+		return WhitelistEntry{}, "Address is not whitelisted"
+	}
+
+	if entry.RequireTag && dest.Tag == "" {
+		return WhitelistEntry{}, "Address requires a destination tag"
+	}
+
+	return entry, ""
+}
+
+// remaining returns the amount of the currency left under today's limit. It
+// must be called with the lock held.
+func (g *WithdrawalGuard) remaining(currency Currency) AmountWhole {
+	g.rollDay()
+
+	remaining := g.limits[currency] - g.spent[currency]
+
+	now := time.Now()
+	for id, pw := range g.pending {
+		if now.After(pw.Expires) {
+			delete(g.pending, id)
+			continue
+		}
+		if pw.Currency == currency {
+			remaining -= pw.Amount
+		}
+	}
+
+	for _, pw := range g.approving {
+		if pw.Currency == currency {
+			remaining -= pw.Amount
+		}
+	}
+
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
+
+// rollDay resets the amounts withdrawn when the day changes. It must be called
+// with the lock held.
+func (g *WithdrawalGuard) rollDay() {
+	today := time.Now().Format("2006-01-02")
+	if g.day != today {
+		g.day = today
+		g.spent = make(map[Currency]AmountWhole)
+	}
+}
@@ -0,0 +1,57 @@
+package btcmarkets
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestWithdrawalGuardTags(t *testing.T) {
+	const address = "rHb9CJAWyB4rj91VRWn96DkukG4bwdtyTh"
+
+	var sent int
+	c := newStubClient(t, func(w http.ResponseWriter, r *http.Request) {
+		sent++
+		writeJSON(t, w, FundTransferWithdrawCryptoResponse{ErrorCode: "3", ErrorMessage: "Insufficient funds"})
+	})
+
+	g := NewWithdrawalGuard(c)
+	g.SetDailyLimit(CurrencyRipple, 10000000000)
+	for _, tag := range []string{"1", "2"} {
+		if err := g.Allow(WhitelistEntry{Currency: CurrencyRipple, Address: address, Tag: tag}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, tag := range []string{"", "3"} {
+		if _, err := g.Prepare(100000000, CurrencyRipple, CryptoDestination{Address: address, Tag: tag}); err == nil {
+			t.Errorf("Prepare with tag %q error = nil, want a rejection", tag)
+		}
+	}
+
+	pw, err := g.Prepare(100000000, CurrencyRipple, CryptoDestination{Address: address, Tag: "2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := g.Remaining(CurrencyRipple); got != 9900000000 {
+		t.Errorf("Remaining() = %d, want 9900000000 while prepared", got)
+	}
+
+	// A rejected withdrawal is returned to the limit.
+	if _, err := g.Confirm(pw.ID); err == nil {
+		t.Fatal("Confirm error = nil, want the withdrawal error")
+	}
+	if sent != 1 {
+		t.Errorf("sent %d withdrawals, want 1", sent)
+	}
+	if got := g.Remaining(CurrencyRipple); got != 10000000000 {
+		t.Errorf("Remaining() = %d, want 10000000000 after a rejected withdrawal", got)
+	}
+
+	g.Revoke(CurrencyRipple, address+"?dt=1")
+	if _, err := g.Prepare(100000000, CurrencyRipple, CryptoDestination{Address: address, Tag: "1"}); err == nil {
+		t.Error("Prepare to a revoked tag error = nil, want a rejection")
+	}
+	if _, err := g.Prepare(100000000, CurrencyRipple, CryptoDestination{Address: address, Tag: "2"}); err != nil {
+		t.Errorf("Prepare to a tag still whitelisted: %v", err)
+	}
+}